@@ -0,0 +1,196 @@
+package analysis
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	defaultAttack  = 0.01 // Seconds for an envelope to rise towards a louder input
+	defaultRelease = 0.25 // Seconds for an envelope to fall towards a quieter input
+	peakDecay      = 0.5  // Fraction of the running peak kept after ten seconds
+	noiseFloor     = 1e-4 // Peaks never fall below this, so silence stays at zero
+)
+
+// Band is a frequency range tracked by a BandAnalyzer. Attack and Release are
+// the envelope follower time constants in seconds; zero picks the defaults.
+type Band struct {
+	Name    string
+	Low     float64
+	High    float64
+	Attack  float64
+	Release float64
+}
+
+// DefaultBands is the classic six-band split used by mixing engineers.
+var DefaultBands = []Band{
+	{Name: "sub", Low: 20, High: 60},
+	{Name: "bass", Low: 60, High: 250},
+	{Name: "lowmid", Low: 250, High: 1000},
+	{Name: "highmid", Low: 1000, High: 4000, Release: 0.15},
+	{Name: "presence", Low: 4000, High: 8000, Release: 0.1},
+	{Name: "air", Low: 8000, High: 20000, Release: 0.08},
+}
+
+// LogBands splits the range between low and high into n logarithmically
+// spaced bands named "band0", "band1", and so on.
+func LogBands(n int, low, high float64) []Band {
+	if n < 1 || low <= 0 || high <= low {
+		return nil
+	}
+
+	bands := make([]Band, n)
+	ratio := math.Pow(high/low, 1/float64(n))
+	edge := low
+	for i := range bands {
+		bands[i] = Band{
+			Name: "band" + strconv.Itoa(i),
+			Low:  edge,
+			High: edge * ratio,
+		}
+		edge *= ratio
+	}
+	bands[n-1].High = high
+	return bands
+}
+
+// Envelope is a one-pole attack/release follower.
+type Envelope struct {
+	attack  float64
+	release float64
+	value   float64
+}
+
+// NewEnvelope returns a follower with the given time constants in seconds,
+// advanced updateRate times per second.
+func NewEnvelope(attack, release, updateRate float64) *Envelope {
+	return &Envelope{
+		attack:  timeCoefficient(attack, updateRate),
+		release: timeCoefficient(release, updateRate),
+	}
+}
+
+// Process moves the envelope towards input and returns the new value.
+func (e *Envelope) Process(input float64) float64 {
+	coefficient := e.release
+	if input > e.value {
+		coefficient = e.attack
+	}
+	e.value = coefficient*e.value + (1-coefficient)*input
+	return e.value
+}
+
+// Value returns the current envelope level.
+func (e *Envelope) Value() float64 {
+	return e.value
+}
+
+// BandAnalyzer turns audio frames into smoothed, normalized per-band energies.
+type BandAnalyzer struct {
+	bands      []Band
+	sampleRate int
+	envelopes  []*Envelope
+	peaks      []float64
+	peakDecay  float64
+	levels     []float64
+}
+
+// NewBandAnalyzer creates an analyzer for bands, expecting Process to be
+// called updateRate times per second with frames sampled at sampleRate.
+func NewBandAnalyzer(bands []Band, sampleRate int, updateRate float64) *BandAnalyzer {
+	b := &BandAnalyzer{
+		bands:      append([]Band(nil), bands...),
+		sampleRate: sampleRate,
+		envelopes:  make([]*Envelope, len(bands)),
+		peaks:      make([]float64, len(bands)),
+		peakDecay:  math.Pow(peakDecay, 1/(10*updateRate)),
+		levels:     make([]float64, len(bands)),
+	}
+
+	for i, band := range b.bands {
+		attack, release := band.Attack, band.Release
+		if attack <= 0 {
+			attack = defaultAttack
+		}
+		if release <= 0 {
+			release = defaultRelease
+		}
+		b.envelopes[i] = NewEnvelope(attack, release, updateRate)
+		b.peaks[i] = noiseFloor
+	}
+	return b
+}
+
+// Bands returns the bands tracked by the analyzer.
+func (b *BandAnalyzer) Bands() []Band {
+	return b.bands
+}
+
+// Process analyses one frame of samples and returns the level of each band in
+// the range 0 to 1, relative to the recent peak of that band. The returned
+// slice is reused between calls.
+func (b *BandAnalyzer) Process(samples []float64) []float64 {
+	return b.ProcessSpectrum(Spectrum(samples), len(samples))
+}
+
+// ProcessSpectrum is Process for callers that already computed the spectrum of
+// a frame of frameSize samples.
+func (b *BandAnalyzer) ProcessSpectrum(spectrum []float64, frameSize int) []float64 {
+	for i, band := range b.bands {
+		energy := bandEnergy(spectrum, band, frameSize, b.sampleRate)
+		level := b.envelopes[i].Process(energy)
+
+		// Let the peak decay slowly so quiet passages still use the full range
+		b.peaks[i] = math.Max(noiseFloor, math.Max(level, b.peaks[i]*b.peakDecay))
+		b.levels[i] = level / b.peaks[i]
+	}
+	return b.levels
+}
+
+// Levels returns the levels computed by the last call to Process.
+func (b *BandAnalyzer) Levels() []float64 {
+	return b.levels
+}
+
+// Level returns the last level of the named band, or zero if it is unknown.
+func (b *BandAnalyzer) Level(name string) float64 {
+	for i, band := range b.bands {
+		if band.Name == name {
+			return b.levels[i]
+		}
+	}
+	return 0
+}
+
+// bandEnergy returns the RMS magnitude of the bins whose centre frequency
+// falls inside band. Bands narrower than a bin use the nearest bin instead.
+func bandEnergy(spectrum []float64, band Band, frameSize, sampleRate int) float64 {
+	if len(spectrum) == 0 || frameSize == 0 {
+		return 0
+	}
+
+	sumSquares := 0.0
+	count := 0
+	for bin := 1; bin < len(spectrum); bin++ {
+		frequency := BinFrequency(bin, frameSize, sampleRate)
+		if frequency >= band.Low && frequency < band.High {
+			sumSquares += spectrum[bin] * spectrum[bin]
+			count++
+		}
+	}
+
+	if count == 0 {
+		centre := math.Sqrt(band.Low * band.High)
+		bin := int(math.Round(centre * float64(frameSize) / float64(sampleRate)))
+		bin = max(1, min(bin, len(spectrum)-1))
+		return spectrum[bin]
+	}
+	return math.Sqrt(sumSquares / float64(count))
+}
+
+func timeCoefficient(seconds, updateRate float64) float64 {
+	if seconds <= 0 || updateRate <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (seconds * updateRate))
+}
@@ -0,0 +1,117 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/idroz/mezmer/audio"
+)
+
+const testRate = 44100
+
+// generate reads n samples of a test signal, see audio.ParseSignal.
+func generate(t *testing.T, signal string, n int) []float64 {
+	t.Helper()
+	voices, err := audio.ParseSignal(signal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make([]float64, n)
+	audio.NewGenerator(voices, testRate, 1).Read(samples)
+	return samples
+}
+
+func TestEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		attack   float64
+		release  float64
+		from, to float64
+		seconds  float64
+		want     float64
+	}{
+		// A one-pole follower covers 1-1/e of a step in one time constant
+		{"attack", 0.1, 1, 0, 1, 0.1, 1 - 1/math.E},
+		{"release", 0.1, 0.5, 1, 0, 0.5, 1 / math.E},
+		{"instant", 0, 0, 0, 1, 1.0 / 60, 1},
+		{"settled", 0.01, 0.25, 0, 0.5, 1, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnvelope(tt.attack, tt.release, 60)
+			e.value = tt.from
+			for i := 0; i < int(math.Round(tt.seconds*60)); i++ {
+				e.Process(tt.to)
+			}
+			if math.Abs(e.Value()-tt.want) > 0.01 {
+				t.Errorf("Reached %.3f, want %.3f", e.Value(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBandAnalyzer(t *testing.T) {
+	tests := []struct {
+		signal string
+		band   string
+	}{
+		{"sine:40", "sub"},
+		{"sine:120", "bass"},
+		{"sine:500", "lowmid"},
+		{"sine:2000", "highmid"},
+		{"sine:6000", "presence"},
+		{"sine:12000", "air"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.signal, func(t *testing.T) {
+			b := NewBandAnalyzer(DefaultBands, testRate, 60)
+			samples := generate(t, tt.signal, 4096)
+			for i := 0; i < 30; i++ {
+				b.Process(samples)
+			}
+
+			loudest := 0
+			for i, e := range b.envelopes {
+				if e.Value() > b.envelopes[loudest].Value() {
+					loudest = i
+				}
+			}
+			if got := b.Bands()[loudest].Name; got != tt.band {
+				t.Errorf("Loudest band is %s, want %s", got, tt.band)
+			}
+			if level := b.Level(tt.band); math.Abs(level-1) > 0.01 {
+				t.Errorf("A steady tone reads %.3f in its band, want 1", level)
+			}
+		})
+	}
+}
+
+func TestLogBands(t *testing.T) {
+	tests := []struct {
+		n         int
+		low, high float64
+		want      []float64 // Band edges
+	}{
+		{3, 100, 800, []float64{100, 200, 400, 800}},
+		{1, 20, 20000, []float64{20, 20000}},
+		{0, 20, 20000, nil},
+		{2, 400, 200, nil},
+	}
+	for _, tt := range tests {
+		bands := LogBands(tt.n, tt.low, tt.high)
+		if tt.want == nil {
+			if bands != nil {
+				t.Errorf("LogBands(%d, %g, %g) = %v, want nil", tt.n, tt.low, tt.high, bands)
+			}
+			continue
+		}
+		if len(bands) != len(tt.want)-1 {
+			t.Fatalf("LogBands(%d, %g, %g) made %d bands", tt.n, tt.low, tt.high, len(bands))
+		}
+		for i, band := range bands {
+			if math.Abs(band.Low-tt.want[i]) > 1e-9 || math.Abs(band.High-tt.want[i+1]) > 1e-9 {
+				t.Errorf("Band %d spans %g to %g, want %g to %g", i, band.Low, band.High, tt.want[i], tt.want[i+1])
+			}
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package analysis
+
+import (
+	"math"
+)
+
+const (
+	minChromaFrequency = 55.0   // Bins below this are ignored by Chroma
+	maxChromaFrequency = 5000.0 // Bins above this are ignored by Chroma
+	minChordStrength   = 0.6    // Chord template similarity needed to name a chord
+)
+
+// Krumhansl-Kessler key profiles, starting at the tonic.
+var (
+	majorProfile = [12]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [12]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// Triad templates, starting at the root.
+var (
+	majorTriad = [12]float64{1, 0, 0, 0, 1, 0, 0, 1, 0, 0, 0, 0}
+	minorTriad = [12]float64{1, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0}
+)
+
+// Chroma folds a magnitude spectrum of a frame of frameSize samples into 12
+// pitch classes, C first, scaled so the strongest class is 1. Bins too wide
+// to tell neighbouring semitones apart are skipped, so short frames only use
+// the upper part of the spectrum: 512 samples at 44.1 kHz start at about
+// 1.45 kHz, 8192 at about 90 Hz.
+func Chroma(spectrum []float64, frameSize, sampleRate int) [12]float64 {
+	var chroma [12]float64
+	if frameSize == 0 {
+		return chroma
+	}
+
+	binWidth := float64(sampleRate) / float64(frameSize)
+	// A semitone is about 6% of its frequency, so it spans a full bin from here up
+	lowest := math.Max(minChromaFrequency, binWidth/(math.Pow(2, 1.0/12)-1))
+
+	for bin := 1; bin < len(spectrum); bin++ {
+		frequency := BinFrequency(bin, frameSize, sampleRate)
+		if frequency < lowest || frequency > maxChromaFrequency {
+			continue
+		}
+
+		// Share the bin between the two nearest pitch classes
+		semitones := 12 * math.Log2(frequency/referencePitch)
+		lower := math.Floor(semitones)
+		weight := semitones - lower
+		energy := spectrum[bin] * spectrum[bin]
+		chroma[pitchClassOf(int(lower))] += energy * (1 - weight)
+		chroma[pitchClassOf(int(lower)+1)] += energy * weight
+	}
+
+	return normalizeChroma(chroma)
+}
+
+// PitchClassHue maps a pitch class onto the colour wheel in degrees. With
+// fifths set, the classes follow the circle of fifths, so closely related keys
+// and chords get neighbouring colours; otherwise they run chromatically.
+func PitchClassHue(pitchClass int, fifths bool) float64 {
+	pitchClass = (pitchClass%12 + 12) % 12
+	if fifths {
+		pitchClass = pitchClass * 7 % 12
+	}
+	return float64(pitchClass) * 30
+}
+
+// Key is an estimated musical key.
+type Key struct {
+	Tonic      int  // Pitch class of the tonic, 0 for C
+	Minor      bool // Minor rather than major
+	Confidence float64
+}
+
+// String formats the key as e.g. "A minor", or "-" when there is none.
+func (k Key) String() string {
+	if k.Confidence <= 0 {
+		return "-"
+	}
+	if k.Minor {
+		return noteNames[k.Tonic] + " minor"
+	}
+	return noteNames[k.Tonic] + " major"
+}
+
+// Chord is an estimated triad.
+type Chord struct {
+	Root       int  // Pitch class of the root, 0 for C
+	Minor      bool // Minor rather than major triad
+	Confidence float64
+}
+
+// String formats the chord as e.g. "Am", or "N" when no chord is heard.
+func (c Chord) String() string {
+	if c.Confidence <= 0 {
+		return "N"
+	}
+	if c.Minor {
+		return noteNames[c.Root] + "m"
+	}
+	return noteNames[c.Root]
+}
+
+// EstimateKey correlates a chromagram with the 24 major and minor key
+// profiles and returns the best match. Confidence is the correlation.
+func EstimateKey(chroma [12]float64) Key {
+	best := Key{}
+	for tonic := 0; tonic < 12; tonic++ {
+		if r := correlation(chroma, rotate(majorProfile, tonic)); r > best.Confidence {
+			best = Key{Tonic: tonic, Confidence: r}
+		}
+		if r := correlation(chroma, rotate(minorProfile, tonic)); r > best.Confidence {
+			best = Key{Tonic: tonic, Minor: true, Confidence: r}
+		}
+	}
+	return best
+}
+
+// EstimateChord matches a chromagram against the 24 major and minor triads.
+// Confidence is the cosine similarity, zero when nothing matches well.
+func EstimateChord(chroma [12]float64) Chord {
+	best := Chord{}
+	for root := 0; root < 12; root++ {
+		if s := cosine(chroma, rotate(majorTriad, root)); s > best.Confidence {
+			best = Chord{Root: root, Confidence: s}
+		}
+		if s := cosine(chroma, rotate(minorTriad, root)); s > best.Confidence {
+			best = Chord{Root: root, Minor: true, Confidence: s}
+		}
+	}
+	if best.Confidence < minChordStrength {
+		return Chord{}
+	}
+	return best
+}
+
+// HarmonyTracker averages chromagrams over sliding windows and estimates the
+// current chord over a short window and the key over a long one.
+type HarmonyTracker struct {
+	sampleRate int
+	chord      chromaWindow
+	key        chromaWindow
+	chroma     [12]float64
+}
+
+// NewHarmonyTracker creates a tracker whose chord and key windows span the
+// given number of frames.
+func NewHarmonyTracker(sampleRate, chordFrames, keyFrames int) *HarmonyTracker {
+	return &HarmonyTracker{
+		sampleRate: sampleRate,
+		chord:      newChromaWindow(chordFrames),
+		key:        newChromaWindow(keyFrames),
+	}
+}
+
+// Process adds the spectrum of a frame of frameSize samples to the windows.
+func (h *HarmonyTracker) Process(spectrum []float64, frameSize int) {
+	chroma := Chroma(spectrum, frameSize, h.sampleRate)
+	h.chord.add(chroma)
+	h.key.add(chroma)
+	h.chroma = normalizeChroma(h.chord.sum)
+}
+
+// Chroma returns the chromagram averaged over the chord window.
+func (h *HarmonyTracker) Chroma() [12]float64 {
+	return h.chroma
+}
+
+// Chord returns the chord estimated over the chord window.
+func (h *HarmonyTracker) Chord() Chord {
+	return EstimateChord(h.chroma)
+}
+
+// Key returns the key estimated over the key window.
+func (h *HarmonyTracker) Key() Key {
+	return EstimateKey(normalizeChroma(h.key.sum))
+}
+
+// chromaWindow keeps a running sum of the last len(frames) chromagrams.
+type chromaWindow struct {
+	frames [][12]float64
+	next   int
+	sum    [12]float64
+}
+
+func newChromaWindow(size int) chromaWindow {
+	return chromaWindow{frames: make([][12]float64, max(1, size))}
+}
+
+func (w *chromaWindow) add(chroma [12]float64) {
+	for i := range w.sum {
+		w.sum[i] += chroma[i] - w.frames[w.next][i]
+		// Guard against drift from repeated floating point subtraction
+		w.sum[i] = math.Max(0, w.sum[i])
+	}
+	w.frames[w.next] = chroma
+	w.next = (w.next + 1) % len(w.frames)
+}
+
+func normalizeChroma(chroma [12]float64) [12]float64 {
+	peak := 0.0
+	for _, value := range chroma {
+		peak = math.Max(peak, value)
+	}
+	if peak == 0 {
+		return chroma
+	}
+	for i := range chroma {
+		chroma[i] /= peak
+	}
+	return chroma
+}
+
+// rotate shifts a profile that starts at pitch class 0 to start at root.
+func rotate(profile [12]float64, root int) [12]float64 {
+	var rotated [12]float64
+	for i, value := range profile {
+		rotated[(i+root)%12] = value
+	}
+	return rotated
+}
+
+func correlation(a, b [12]float64) float64 {
+	meanA, meanB := 0.0, 0.0
+	for i := range a {
+		meanA += a[i] / 12
+		meanB += b[i] / 12
+	}
+
+	covariance, varianceA, varianceB := 0.0, 0.0, 0.0
+	for i := range a {
+		covariance += (a[i] - meanA) * (b[i] - meanB)
+		varianceA += (a[i] - meanA) * (a[i] - meanA)
+		varianceB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varianceA == 0 || varianceB == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceA*varianceB)
+}
+
+func cosine(a, b [12]float64) float64 {
+	dot, normA, normB := 0.0, 0.0, 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+func pitchClassOf(semitonesFromA int) int {
+	// Semitones are counted from A, pitch classes from C
+	return ((semitonesFromA+9)%12 + 12) % 12
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"testing"
+)
+
+func TestChromaTriads(t *testing.T) {
+	tests := []struct {
+		signal string
+		size   int
+		want   string
+	}{
+		{"sine:261.63+sine:329.63+sine:392", chromaHistory, "C"},
+		{"sine:220+sine:261.63+sine:329.63", chromaHistory, "Am"},
+		{"sine:196+sine:246.94+sine:293.66", chromaHistory, "G"},
+		{"sine:130.81+sine:155.56+sine:196", chromaHistory, "Cm"},
+		{"sine:130.81+sine:155.56+sine:196", 512, "N"}, // Below what a single frame resolves
+		{"white", chromaHistory, "N"},
+	}
+	for _, tt := range tests {
+		samples := generate(t, tt.signal, tt.size)
+		chroma := Chroma(Spectrum(samples), tt.size, testRate)
+		if chord := EstimateChord(chroma); chord.String() != tt.want {
+			t.Errorf("%s over %d samples: heard %s, want %s (chroma %.2f)", tt.signal, tt.size, chord, tt.want, chroma)
+		}
+	}
+}
+
+func TestEstimateKey(t *testing.T) {
+	tests := []struct {
+		chords []string // Signals played in turn
+		want   string
+	}{
+		// I, IV and V
+		{[]string{"sine:196+sine:246.94+sine:293.66", "sine:261.63+sine:329.63+sine:392", "sine:293.66+sine:369.99+sine:440"}, "G major"},
+		// i, iv and v
+		{[]string{"sine:220+sine:261.63+sine:329.63", "sine:293.66+sine:349.23+sine:440", "sine:329.63+sine:392+sine:493.88"}, "A minor"},
+	}
+	for _, tt := range tests {
+		h := NewHarmonyTracker(testRate, chordWindow, keyWindow)
+		for _, chord := range tt.chords {
+			samples := generate(t, chord, chromaHistory)
+			for i := 0; i < 10; i++ {
+				h.Process(Spectrum(samples), chromaHistory)
+			}
+		}
+		if key := h.Key(); key.String() != tt.want {
+			t.Errorf("%v: heard %s, want %s", tt.chords, key, tt.want)
+		}
+	}
+
+	// The profiles themselves match their own key perfectly
+	for tonic := 0; tonic < 12; tonic++ {
+		if key := EstimateKey(rotate(minorProfile, tonic)); key.Tonic != tonic || !key.Minor || key.Confidence < 0.999 {
+			t.Errorf("The minor profile on %s reads as %s (%.3f)", noteNames[tonic], key, key.Confidence)
+		}
+	}
+}
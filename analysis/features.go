@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"math"
+)
+
+const (
+	rolloffFraction  = 0.85 // Share of spectral energy below the rolloff frequency
+	featureSmoothing = 0.1  // Seconds for features to settle after a change
+	lowestFrequency  = 20.0 // Bottom of the log scale used to normalize frequencies
+)
+
+// Features describes the timbre of one frame.
+type Features struct {
+	Centroid         float64 // Spectral centre of mass in Hz, perceived brightness
+	Spread           float64 // Standard deviation around the centroid in Hz
+	Flatness         float64 // Geometric over arithmetic mean power, 0 (tonal) to 1 (white noise)
+	Rolloff          float64 // Frequency in Hz below which 85% of the spectral energy lies
+	Flux             float64 // Sum of magnitude increases since the previous frame, in spectrum units
+	ZeroCrossingRate float64 // Sign changes per second in Hz, high for noisy or bright sounds
+}
+
+// FeatureExtractor computes smoothed spectral features frame by frame, along
+// with a copy normalized to the range 0 to 1 for mapping onto visuals.
+type FeatureExtractor struct {
+	sampleRate  int
+	previous    []float64
+	smoothing   float64
+	fluxPeak    float64
+	fluxDecay   float64
+	features    Features
+	normalized  Features
+	initialized bool
+}
+
+// NewFeatureExtractor creates an extractor expecting Process to be called
+// updateRate times per second with frames sampled at sampleRate.
+func NewFeatureExtractor(sampleRate int, updateRate float64) *FeatureExtractor {
+	return &FeatureExtractor{
+		sampleRate: sampleRate,
+		smoothing:  timeCoefficient(featureSmoothing, updateRate),
+		fluxPeak:   noiseFloor,
+		fluxDecay:  math.Pow(peakDecay, 1/(10*updateRate)),
+	}
+}
+
+// Process computes the features of a frame from its samples and magnitude
+// spectrum, and returns the smoothed and normalized features.
+func (e *FeatureExtractor) Process(samples, spectrum []float64) (Features, Features) {
+	raw := Features{
+		ZeroCrossingRate: zeroCrossingRate(samples) * float64(e.sampleRate),
+		Flux:             spectralFlux(e.previous, spectrum),
+	}
+	raw.Centroid, raw.Spread = spectralCentroid(spectrum, len(samples), e.sampleRate)
+	raw.Flatness = spectralFlatness(spectrum)
+	raw.Rolloff = spectralRolloff(spectrum, len(samples), e.sampleRate)
+	e.previous = append(e.previous[:0], spectrum...)
+
+	if !e.initialized {
+		e.features = raw
+		e.initialized = true
+	} else {
+		e.features = Features{
+			Centroid:         smooth(e.features.Centroid, raw.Centroid, e.smoothing),
+			Spread:           smooth(e.features.Spread, raw.Spread, e.smoothing),
+			Flatness:         smooth(e.features.Flatness, raw.Flatness, e.smoothing),
+			Rolloff:          smooth(e.features.Rolloff, raw.Rolloff, e.smoothing),
+			Flux:             smooth(e.features.Flux, raw.Flux, e.smoothing),
+			ZeroCrossingRate: smooth(e.features.ZeroCrossingRate, raw.ZeroCrossingRate, e.smoothing),
+		}
+	}
+
+	nyquist := float64(e.sampleRate) / 2
+	e.fluxPeak = math.Max(noiseFloor, math.Max(e.features.Flux, e.fluxPeak*e.fluxDecay))
+	e.normalized = Features{
+		Centroid:         logScale(e.features.Centroid, nyquist),
+		Spread:           math.Min(1, e.features.Spread/(nyquist/2)),
+		Flatness:         e.features.Flatness,
+		Rolloff:          logScale(e.features.Rolloff, nyquist),
+		Flux:             e.features.Flux / e.fluxPeak,
+		ZeroCrossingRate: math.Min(1, e.features.ZeroCrossingRate/nyquist),
+	}
+	return e.features, e.normalized
+}
+
+// Brightness returns the normalized spectral centroid, 0 for dull and 1 for
+// bright sounds. Call it on the normalized features.
+func (f Features) Brightness() float64 {
+	return f.Centroid
+}
+
+// Noisiness returns the spectral flatness, 0 for pure tones and 1 for noise.
+func (f Features) Noisiness() float64 {
+	return f.Flatness
+}
+
+func spectralCentroid(spectrum []float64, frameSize, sampleRate int) (float64, float64) {
+	weighted, total := 0.0, 0.0
+	for bin := 1; bin < len(spectrum); bin++ {
+		weighted += BinFrequency(bin, frameSize, sampleRate) * spectrum[bin]
+		total += spectrum[bin]
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	centroid := weighted / total
+
+	variance := 0.0
+	for bin := 1; bin < len(spectrum); bin++ {
+		delta := BinFrequency(bin, frameSize, sampleRate) - centroid
+		variance += delta * delta * spectrum[bin]
+	}
+	return centroid, math.Sqrt(variance / total)
+}
+
+func spectralFlatness(spectrum []float64) float64 {
+	if len(spectrum) < 2 {
+		return 0
+	}
+
+	logSum, sum := 0.0, 0.0
+	for bin := 1; bin < len(spectrum); bin++ {
+		power := spectrum[bin]*spectrum[bin] + 1e-12
+		logSum += math.Log(power)
+		sum += power
+	}
+	count := float64(len(spectrum) - 1)
+	return math.Min(1, math.Exp(logSum/count)/(sum/count))
+}
+
+func spectralRolloff(spectrum []float64, frameSize, sampleRate int) float64 {
+	total := 0.0
+	for bin := 1; bin < len(spectrum); bin++ {
+		total += spectrum[bin] * spectrum[bin]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	cumulative := 0.0
+	for bin := 1; bin < len(spectrum); bin++ {
+		cumulative += spectrum[bin] * spectrum[bin]
+		if cumulative >= rolloffFraction*total {
+			return BinFrequency(bin, frameSize, sampleRate)
+		}
+	}
+	return BinFrequency(len(spectrum)-1, frameSize, sampleRate)
+}
+
+func spectralFlux(previous, spectrum []float64) float64 {
+	if len(previous) != len(spectrum) {
+		return 0
+	}
+	flux := 0.0
+	for bin := range spectrum {
+		if delta := spectrum[bin] - previous[bin]; delta > 0 {
+			flux += delta
+		}
+	}
+	return flux
+}
+
+// zeroCrossingRate returns the fraction of adjacent sample pairs whose signs differ.
+func zeroCrossingRate(samples []float64) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] >= 0) != (samples[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(samples)-1)
+}
+
+// logScale maps a frequency onto 0 to 1 logarithmically between 20 Hz and high.
+func logScale(frequency, high float64) float64 {
+	if frequency <= lowestFrequency {
+		return 0
+	}
+	return math.Min(1, math.Log(frequency/lowestFrequency)/math.Log(high/lowestFrequency))
+}
+
+func smooth(previous, current, coefficient float64) float64 {
+	return coefficient*previous + (1-coefficient)*current
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	const size = 4096
+	binWidth := float64(testRate) / size
+	tests := []struct {
+		signal           string
+		centroid         float64 // Hz
+		centroidError    float64
+		flatness         [2]float64 // Range
+		zeroCrossingRate float64    // Hz
+	}{
+		// A sine crosses zero twice a cycle, with all its energy in one place
+		{"sine:1000", 1000, binWidth, [2]float64{0, 0.01}, 2000},
+		{"sine:5000", 5000, binWidth, [2]float64{0, 0.01}, 10000},
+		// White noise is flat, centred on half of Nyquist, and changes sign every other sample
+		{"white", testRate / 4, 1000, [2]float64{0.4, 1}, testRate / 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.signal, func(t *testing.T) {
+			samples := generate(t, tt.signal, size)
+			e := NewFeatureExtractor(testRate, 60)
+			features, _ := e.Process(samples, Spectrum(samples))
+
+			if math.Abs(features.Centroid-tt.centroid) > tt.centroidError {
+				t.Errorf("Centroid %.0f Hz, want %.0f Hz", features.Centroid, tt.centroid)
+			}
+			if features.Flatness < tt.flatness[0] || features.Flatness > tt.flatness[1] {
+				t.Errorf("Flatness %.3f, want %.2f to %.2f", features.Flatness, tt.flatness[0], tt.flatness[1])
+			}
+			if math.Abs(features.ZeroCrossingRate-tt.zeroCrossingRate) > tt.zeroCrossingRate*0.05 {
+				t.Errorf("Zero crossing rate %.0f Hz, want %.0f Hz", features.ZeroCrossingRate, tt.zeroCrossingRate)
+			}
+		})
+	}
+}
+
+func TestSpectralFlux(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []float64
+		spectrum []float64
+		want     float64
+	}{
+		{"steady", []float64{0, 1, 2}, []float64{0, 1, 2}, 0},
+		{"onset", []float64{0, 0, 0}, []float64{0, 1, 2}, 3},
+		{"decay", []float64{0, 1, 2}, []float64{0, 0, 0}, 0}, // Only increases count
+		{"mixed", []float64{1, 0, 2}, []float64{0, 1, 2}, 1},
+		{"first frame", nil, []float64{0, 1, 2}, 0},
+	}
+	for _, tt := range tests {
+		if got := spectralFlux(tt.previous, tt.spectrum); got != tt.want {
+			t.Errorf("%s: flux %g, want %g", tt.name, got, tt.want)
+		}
+	}
+
+	// The flux of a real onset normalizes to 1 against its own peak
+	e := NewFeatureExtractor(testRate, 60)
+	silence := make([]float64, 1024)
+	tone := generate(t, "sine:440", 1024)
+	e.Process(silence, Spectrum(silence))
+	_, normalized := e.Process(tone, Spectrum(tone))
+	if normalized.Flux != 1 {
+		t.Errorf("A note after silence has a normalized flux of %.3f, want 1", normalized.Flux)
+	}
+}
@@ -0,0 +1,102 @@
+package analysis
+
+const (
+	pitchConfidence = 0.8  // Minimum confidence for a detected pitch to be tracked
+	pitchHold       = 15   // Frames a pitch is held through unvoiced frames
+	chordWindow     = 30   // Frames of chroma averaged to estimate the chord
+	keyWindow       = 600  // Frames of chroma averaged to estimate the key
+	pitchHistory    = 2048 // Latest samples searched for a pitch, down to about 43 Hz at 44.1 kHz
+	chromaHistory   = 8192 // Latest samples folded into chroma, resolving semitones down to about 90 Hz
+)
+
+// Frame is everything known about one frame of audio, handed to emitters,
+// renderers and colour mapping.
+type Frame struct {
+	Samples    []float64 // Raw samples, -1 to 1
+	Spectrum   []float64 // Magnitude spectrum, see Spectrum
+	RMS        float64   // Root mean square level of the samples
+	Bands      []float64 // Normalized band levels, in the order of Analyzer.Bands
+	Pitch      Pitch
+	Chroma     [12]float64 // Chromagram averaged over the chord window, C first
+	Chord      Chord
+	Key        Key
+	Features   Features // Smoothed features in their natural units
+	Normalized Features // Smoothed features scaled to 0 to 1
+	bandNames  []Band
+}
+
+// Band returns the normalized level of the named band, or zero if unknown.
+func (f *Frame) Band(name string) float64 {
+	for i, band := range f.bandNames {
+		if band.Name == name {
+			return f.Bands[i]
+		}
+	}
+	return 0
+}
+
+// Analyzer runs every analysis stage on successive frames.
+type Analyzer struct {
+	sampleRate int
+	bands      *BandAnalyzer
+	pitch      *PitchTracker
+	harmony    *HarmonyTracker
+	features   *FeatureExtractor
+	history    []float64 // The last chromaHistory samples, oldest first
+	frame      Frame
+}
+
+// NewAnalyzer creates an analyzer for the given bands, expecting Process to
+// be called updateRate times per second with frames sampled at sampleRate.
+func NewAnalyzer(bands []Band, sampleRate int, updateRate float64) *Analyzer {
+	return &Analyzer{
+		sampleRate: sampleRate,
+		bands:      NewBandAnalyzer(bands, sampleRate, updateRate),
+		pitch:      NewPitchTracker(sampleRate, pitchConfidence, pitchHold),
+		harmony:    NewHarmonyTracker(sampleRate, chordWindow, keyWindow),
+		features:   NewFeatureExtractor(sampleRate, updateRate),
+		history:    make([]float64, chromaHistory),
+	}
+}
+
+// Bands returns the bands tracked by the analyzer.
+func (a *Analyzer) Bands() []Band {
+	return a.bands.Bands()
+}
+
+// Process analyses samples and returns the resulting frame. The frame and its
+// slices are reused by the next call. Pitch and harmony look further back than
+// one frame, over a rolling history of the latest samples, since a short
+// frame can't resolve low notes.
+func (a *Analyzer) Process(samples []float64) *Frame {
+	a.remember(samples)
+	spectrum := Spectrum(samples)
+	a.harmony.Process(Spectrum(a.history), len(a.history))
+
+	a.frame.Samples = samples
+	a.frame.Spectrum = spectrum
+	a.frame.RMS = rms(samples)
+	a.frame.Bands = a.bands.ProcessSpectrum(spectrum, len(samples))
+	a.frame.Pitch = a.pitch.Process(a.history[len(a.history)-pitchHistory:])
+	a.frame.Chroma = a.harmony.Chroma()
+	a.frame.Chord = a.harmony.Chord()
+	a.frame.Key = a.harmony.Key()
+	a.frame.Features, a.frame.Normalized = a.features.Process(samples, spectrum)
+	a.frame.bandNames = a.bands.Bands()
+	return &a.frame
+}
+
+// remember appends samples to the history, dropping the oldest.
+func (a *Analyzer) remember(samples []float64) {
+	if len(samples) >= len(a.history) {
+		copy(a.history, samples[len(samples)-len(a.history):])
+		return
+	}
+	copy(a.history, a.history[len(samples):])
+	copy(a.history[len(a.history)-len(samples):], samples)
+}
+
+// Frame returns the frame produced by the last call to Process.
+func (a *Analyzer) Frame() *Frame {
+	return &a.frame
+}
@@ -0,0 +1,207 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	yinThreshold   = 0.15   // Largest normalized difference accepted as a period
+	minPitch       = 40.0   // Lowest frequency searched for, in Hz
+	maxPitch       = 4000.0 // Highest frequency searched for, in Hz
+	silenceRMS     = 1e-3   // Frames quieter than this have no pitch
+	referencePitch = 440.0  // Frequency of A4 in Hz
+)
+
+var noteNames = [12]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// Note is a frequency expressed in equal-tempered musical terms.
+type Note struct {
+	Name       string  // Pitch class name, e.g. "C#"
+	PitchClass int     // 0 for C up to 11 for B
+	Octave     int     // Scientific pitch notation octave, A4 = 440 Hz
+	Cents      float64 // Deviation from the named note, -50 to +50
+	MIDI       int     // MIDI note number, A4 = 69
+}
+
+// String formats the note as e.g. "A4 +3c".
+func (n Note) String() string {
+	return fmt.Sprintf("%s%d %+.0fc", n.Name, n.Octave, n.Cents)
+}
+
+// Hue maps the note onto the colour wheel in degrees, one full turn per
+// octave, so the same pitch class always gets the same colour.
+func (n Note) Hue() float64 {
+	return math.Mod(PitchClassHue(n.PitchClass, false)+n.Cents*0.3+360, 360)
+}
+
+// NoteFromFrequency converts a frequency in Hz to the nearest note.
+func NoteFromFrequency(frequency float64) Note {
+	if frequency <= 0 {
+		return Note{}
+	}
+
+	semitones := 12 * math.Log2(frequency/referencePitch)
+	nearest := math.Round(semitones)
+	midi := 69 + int(nearest)
+	pitchClass := ((midi % 12) + 12) % 12
+	return Note{
+		Name:       noteNames[pitchClass],
+		PitchClass: pitchClass,
+		Octave:     midi/12 - 1,
+		Cents:      (semitones - nearest) * 100,
+		MIDI:       midi,
+	}
+}
+
+// Pitch is the result of pitch detection on one frame.
+type Pitch struct {
+	Frequency  float64 // Fundamental frequency in Hz, zero when unpitched
+	Confidence float64 // 0 (noise) to 1 (perfectly periodic)
+	Note       Note
+}
+
+// Voiced reports whether a pitch was found.
+func (p Pitch) Voiced() bool {
+	return p.Frequency > 0
+}
+
+// Position maps the pitch onto 0 to 1 on a logarithmic scale between low and
+// high Hz, for driving positions or sizes from melody.
+func (p Pitch) Position(low, high float64) float64 {
+	if !p.Voiced() || low <= 0 || high <= low {
+		return 0
+	}
+	position := math.Log(p.Frequency/low) / math.Log(high/low)
+	return math.Max(0, math.Min(1, position))
+}
+
+// DetectPitch estimates the fundamental frequency of samples with the YIN
+// algorithm. The lowest detectable frequency is sampleRate/(len(samples)/2),
+// about 43 Hz for the 2048 samples the Analyzer keeps at 44.1 kHz.
+func DetectPitch(samples []float64, sampleRate int) Pitch {
+	window := len(samples) / 2
+	tauMin := max(2, int(float64(sampleRate)/maxPitch))
+	tauMax := min(window, int(float64(sampleRate)/minPitch))
+	if tauMax <= tauMin || rms(samples) < silenceRMS {
+		return Pitch{}
+	}
+
+	// Difference function
+	difference := make([]float64, tauMax)
+	for tau := 1; tau < tauMax; tau++ {
+		sum := 0.0
+		for j := 0; j < window; j++ {
+			delta := samples[j] - samples[j+tau]
+			sum += delta * delta
+		}
+		difference[tau] = sum
+	}
+
+	// Cumulative mean normalized difference
+	normalized := make([]float64, tauMax)
+	normalized[0] = 1
+	runningSum := 0.0
+	for tau := 1; tau < tauMax; tau++ {
+		runningSum += difference[tau]
+		if runningSum == 0 {
+			normalized[tau] = 1
+		} else {
+			normalized[tau] = difference[tau] * float64(tau) / runningSum
+		}
+	}
+
+	// First dip below the threshold, followed down to its local minimum
+	best := -1
+	for tau := tauMin; tau < tauMax; tau++ {
+		if normalized[tau] < yinThreshold {
+			for tau+1 < tauMax && normalized[tau+1] < normalized[tau] {
+				tau++
+			}
+			best = tau
+			break
+		}
+	}
+	if best < 0 {
+		// No clear period, fall back to the global minimum with low confidence
+		best = tauMin
+		for tau := tauMin; tau < tauMax; tau++ {
+			if normalized[tau] < normalized[best] {
+				best = tau
+			}
+		}
+	}
+
+	period := parabolicMinimum(normalized, best)
+	frequency := float64(sampleRate) / period
+	return Pitch{
+		Frequency:  frequency,
+		Confidence: math.Max(0, 1-normalized[best]),
+		Note:       NoteFromFrequency(frequency),
+	}
+}
+
+// PitchTracker smooths frame-by-frame pitch estimates, holding the last
+// confident pitch through short unvoiced gaps.
+type PitchTracker struct {
+	sampleRate    int
+	minConfidence float64
+	holdFrames    int
+	unvoiced      int
+	current       Pitch
+}
+
+// NewPitchTracker creates a tracker that only accepts pitches detected with
+// at least minConfidence and forgets them after holdFrames unvoiced frames.
+func NewPitchTracker(sampleRate int, minConfidence float64, holdFrames int) *PitchTracker {
+	return &PitchTracker{
+		sampleRate:    sampleRate,
+		minConfidence: minConfidence,
+		holdFrames:    holdFrames,
+	}
+}
+
+// Process detects the pitch of one frame and returns the tracked pitch.
+func (t *PitchTracker) Process(samples []float64) Pitch {
+	pitch := DetectPitch(samples, t.sampleRate)
+	if pitch.Voiced() && pitch.Confidence >= t.minConfidence {
+		t.current = pitch
+		t.unvoiced = 0
+		return t.current
+	}
+
+	t.unvoiced++
+	if t.unvoiced > t.holdFrames {
+		t.current = Pitch{}
+	}
+	return t.current
+}
+
+// Pitch returns the last tracked pitch.
+func (t *PitchTracker) Pitch() Pitch {
+	return t.current
+}
+
+// parabolicMinimum refines the index of a minimum to sub-sample precision.
+func parabolicMinimum(values []float64, i int) float64 {
+	if i <= 0 || i >= len(values)-1 {
+		return float64(i)
+	}
+	left, centre, right := values[i-1], values[i], values[i+1]
+	denominator := left - 2*centre + right
+	if denominator == 0 {
+		return float64(i)
+	}
+	return float64(i) + 0.5*(left-right)/denominator
+}
+
+func rms(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sumSquares := 0.0
+	for _, sample := range samples {
+		sumSquares += sample * sample
+	}
+	return math.Sqrt(sumSquares / float64(len(samples)))
+}
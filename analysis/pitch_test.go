@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetectPitch(t *testing.T) {
+	tests := []struct {
+		signal string
+		size   int
+		want   float64 // Hz, zero for no confident pitch
+	}{
+		{"sine:55", pitchHistory, 55},
+		{"sine:82.41", pitchHistory, 82.41},
+		{"sine:220", pitchHistory, 220},
+		{"sine:440", pitchHistory, 440},
+		{"sine:1000", pitchHistory, 1000},
+		{"saw:110", pitchHistory, 110},
+		{"square:65.41", pitchHistory, 65.41},
+		{"white", pitchHistory, 0},
+		{"sine:55*0.0001", pitchHistory, 0}, // Below silenceRMS
+		{"sine:55", 512, 0},                 // A single frame is too short for a period of 802 samples
+	}
+	for _, tt := range tests {
+		pitch := DetectPitch(generate(t, tt.signal, tt.size), testRate)
+		if tt.want == 0 {
+			if pitch.Voiced() && pitch.Confidence >= pitchConfidence {
+				t.Errorf("%s over %d samples: detected %.2f Hz with confidence %.2f, want nothing",
+					tt.signal, tt.size, pitch.Frequency, pitch.Confidence)
+			}
+			continue
+		}
+		if math.Abs(pitch.Frequency-tt.want) > tt.want*0.005 || pitch.Confidence < pitchConfidence {
+			t.Errorf("%s over %d samples: detected %.2f Hz with confidence %.2f, want %.2f Hz",
+				tt.signal, tt.size, pitch.Frequency, pitch.Confidence, tt.want)
+		}
+	}
+}
+
+func TestNoteFromFrequency(t *testing.T) {
+	tests := []struct {
+		frequency float64
+		want      string
+		midi      int
+	}{
+		{440, "A4 +0c", 69},
+		{261.63, "C4 +0c", 60},
+		{27.5, "A0 +0c", 21},
+		{452, "A4 +47c", 69},
+		{466.17, "A#4 +0c", 70},
+		{0, "0 +0c", 0}, // No note
+	}
+	for _, tt := range tests {
+		note := NoteFromFrequency(tt.frequency)
+		if note.String() != tt.want || note.MIDI != tt.midi {
+			t.Errorf("%g Hz is %s (MIDI %d), want %s (MIDI %d)", tt.frequency, note, note.MIDI, tt.want, tt.midi)
+		}
+	}
+}
+
+func TestAnalyzerTracksLowPitch(t *testing.T) {
+	// Frames of 735 samples at 60 per second, each far too short on its own for C2
+	a := NewAnalyzer(DefaultBands, testRate, 60)
+	samples := generate(t, "sine:65.41", 735*30)
+	var frame *Frame
+	for i := 0; i < 30; i++ {
+		frame = a.Process(samples[i*735 : (i+1)*735])
+	}
+	if note := frame.Pitch.Note; note.Name != "C" || note.Octave != 2 {
+		t.Errorf("Tracked %s (%.2f Hz), want C2", note, frame.Pitch.Frequency)
+	}
+}
@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+// Spectrum returns the magnitude spectrum of a Hann-windowed frame, one value
+// per bin from DC up to (but not including) Nyquist.
+func Spectrum(samples []float64) []float64 {
+	n := len(samples)
+	if n == 0 {
+		return nil
+	}
+
+	windowed := make([]float64, n)
+	for i, sample := range samples {
+		windowed[i] = sample * hann(i, n)
+	}
+
+	fftResult := fft.FFTReal(windowed)
+	magnitudes := make([]float64, n/2)
+	for i := range magnitudes {
+		// Scale so a full-scale sine reads roughly 1 regardless of frame size
+		magnitudes[i] = cmplx.Abs(fftResult[i]) * 4 / float64(n)
+	}
+	return magnitudes
+}
+
+// BinFrequency returns the centre frequency in Hz of a spectrum bin.
+func BinFrequency(bin, frameSize, sampleRate int) float64 {
+	return float64(bin) * float64(sampleRate) / float64(frameSize)
+}
+
+func hann(i, n int) float64 {
+	if n < 2 {
+		return 1
+	}
+	return 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(n-1)))
+}
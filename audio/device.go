@@ -0,0 +1,274 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minBackoff      = 250 * time.Millisecond // First retry delay after a failure
+	maxBackoff      = 10 * time.Second       // Retry delays double up to this
+	maxReconnects   = 8                      // Reconnect attempts before giving the device up as absent
+	pollingInterval = 250 * time.Millisecond // How often Run steps the state machine
+)
+
+// DeviceState is the stage a DeviceManager is in.
+type DeviceState int
+
+const (
+	DeviceAbsent       DeviceState = iota // No matching device is plugged in
+	DeviceOpening                         // A matching device was found and is being opened
+	DeviceRunning                         // Audio is streaming
+	DeviceFailed                          // Opening failed, waiting to retry
+	DeviceReconnecting                    // The stream stopped unexpectedly, waiting for the device to come back
+)
+
+func (s DeviceState) String() string {
+	switch s {
+	case DeviceAbsent:
+		return "absent"
+	case DeviceOpening:
+		return "opening"
+	case DeviceRunning:
+		return "running"
+	case DeviceFailed:
+		return "failed"
+	case DeviceReconnecting:
+		return "reconnecting"
+	}
+	return fmt.Sprintf("DeviceState(%d)", int(s))
+}
+
+// DeviceInfo identifies a capture device. IDs are compared by value, so the
+// same device keeps its identity across enumerations.
+type DeviceInfo struct {
+	ID   string
+	Name string
+}
+
+// Stream is an open, running capture device.
+type Stream interface {
+	Close()
+}
+
+// Enumerator lists and opens capture devices.
+type Enumerator interface {
+	Devices() ([]DeviceInfo, error)
+	// Open starts capturing from device, calling onData with mono samples
+	// and onStop if the stream stops on its own, e.g. when it is unplugged.
+	Open(device DeviceInfo, onData func([]float64), onStop func()) (Stream, error)
+}
+
+// DeviceStatus is a snapshot of a DeviceManager for display.
+type DeviceStatus struct {
+	State   DeviceState
+	Device  DeviceInfo // The device in use or last used, if any
+	Err     error      // The last error, if any
+	RetryAt time.Time  // When the next attempt happens in the failed and reconnecting states
+}
+
+// DeviceManager keeps a matching capture device streaming into a Ring,
+// reopening it with backoff when it fails or is unplugged and replugged.
+type DeviceManager struct {
+	enumerator Enumerator
+	match      func(name string) bool
+	ring       *Ring
+	now        func() time.Time
+
+	mu       sync.Mutex
+	state    DeviceState
+	device   DeviceInfo
+	stream   Stream
+	err      error
+	attempts int
+	retryAt  time.Time
+	streamID int64 // Incremented per stream, so stale stop callbacks are ignored
+
+	// Set to the ID of a stream by its stop callback. The callback runs on
+	// the audio thread, possibly while Close waits on it, so it never locks.
+	stoppedID atomic.Int64
+}
+
+// NewDeviceManager creates a manager streaming the first device whose name
+// satisfies match into ring.
+func NewDeviceManager(enumerator Enumerator, match func(name string) bool, ring *Ring) *DeviceManager {
+	return &DeviceManager{
+		enumerator: enumerator,
+		match:      match,
+		ring:       ring,
+		now:        time.Now,
+		state:      DeviceAbsent,
+	}
+}
+
+// MatchNames returns a matcher accepting any of names exactly.
+func MatchNames(names ...string) func(string) bool {
+	return func(name string) bool {
+		for _, n := range names {
+			if name == n {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Status returns the current state for display.
+func (m *DeviceManager) Status() DeviceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return DeviceStatus{State: m.state, Device: m.device, Err: m.err, RetryAt: m.retryAt}
+}
+
+// Run steps the state machine until ctx is cancelled, then closes the stream.
+func (m *DeviceManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollingInterval)
+	defer ticker.Stop()
+
+	for {
+		m.Step()
+		select {
+		case <-ctx.Done():
+			m.Close()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops streaming and returns the manager to the absent state.
+func (m *DeviceManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeStream()
+	m.setState(DeviceAbsent)
+}
+
+// Step advances the state machine once. Run calls it periodically; tests
+// call it directly.
+func (m *DeviceManager) Step() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch m.state {
+	case DeviceAbsent:
+		if device, ok := m.find(""); ok {
+			m.device = device
+			m.attempts = 0
+			m.setState(DeviceOpening)
+			m.open()
+		}
+	case DeviceOpening:
+		m.open()
+	case DeviceRunning:
+		if m.stoppedID.Load() == m.streamID {
+			// The backend stopped the stream, usually because the device went away
+			m.closeStream()
+			m.err = fmt.Errorf("%s stopped unexpectedly", m.device.Name)
+			m.attempts = 1
+			m.setState(DeviceReconnecting)
+			m.retryAt = m.now().Add(backoff(m.attempts))
+			return
+		}
+		if _, ok := m.find(m.device.ID); !ok {
+			m.closeStream()
+			m.setState(DeviceAbsent)
+		}
+	case DeviceFailed, DeviceReconnecting:
+		if m.now().Before(m.retryAt) {
+			return
+		}
+		// Prefer the device we had, but take any match after a replug
+		device, ok := m.find(m.device.ID)
+		if !ok {
+			device, ok = m.find("")
+		}
+		if ok {
+			m.device = device
+			m.setState(DeviceOpening)
+			m.open()
+			return
+		}
+		m.attempts++
+		if m.attempts > maxReconnects {
+			m.setState(DeviceAbsent)
+			return
+		}
+		m.retryAt = m.now().Add(backoff(m.attempts))
+	}
+}
+
+// setState moves to state, logging the transition.
+func (m *DeviceManager) setState(state DeviceState) {
+	if state == m.state {
+		return
+	}
+	m.state = state
+	if m.err != nil && (state == DeviceFailed || state == DeviceReconnecting) {
+		log.Printf("Capture device %s: %s (%v)", m.device.Name, state, m.err)
+	} else {
+		log.Printf("Capture device %s: %s", m.device.Name, state)
+	}
+}
+
+// open tries to start the current device, moving to running or failed.
+func (m *DeviceManager) open() {
+	m.streamID++
+	id := m.streamID
+
+	stream, err := m.enumerator.Open(m.device, m.ring.Write, func() {
+		m.stoppedID.Store(id)
+	})
+	if err != nil {
+		m.err = err
+		m.attempts++
+		m.setState(DeviceFailed)
+		m.retryAt = m.now().Add(backoff(m.attempts))
+		return
+	}
+
+	m.stream = stream
+	m.err = nil
+	m.attempts = 0
+	m.setState(DeviceRunning)
+}
+
+// closeStream closes the current stream, if any, ignoring its stop callback.
+func (m *DeviceManager) closeStream() {
+	m.streamID++
+	if m.stream != nil {
+		m.stream.Close()
+		m.stream = nil
+	}
+}
+
+// find looks up a device by ID, or the first matching device when id is empty.
+func (m *DeviceManager) find(id string) (DeviceInfo, bool) {
+	devices, err := m.enumerator.Devices()
+	if err != nil {
+		m.err = err
+		return DeviceInfo{}, false
+	}
+	for _, device := range devices {
+		if !m.match(device.Name) {
+			continue
+		}
+		if id == "" || device.ID == id {
+			return device, true
+		}
+	}
+	return DeviceInfo{}, false
+}
+
+// backoff returns the delay before retry number attempts.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
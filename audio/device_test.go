@@ -0,0 +1,212 @@
+package audio
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeEnumerator stands in for the audio backend.
+type fakeEnumerator struct {
+	devices  []DeviceInfo
+	failures int // Number of upcoming Open calls that fail
+	opened   int
+	streams  []*fakeStream
+}
+
+type fakeStream struct {
+	onStop func()
+	closed bool
+}
+
+func (s *fakeStream) Close() {
+	s.closed = true
+}
+
+func (e *fakeEnumerator) Devices() ([]DeviceInfo, error) {
+	return append([]DeviceInfo(nil), e.devices...), nil
+}
+
+func (e *fakeEnumerator) Open(device DeviceInfo, onData func([]float64), onStop func()) (Stream, error) {
+	if e.failures > 0 {
+		e.failures--
+		return nil, errors.New("device busy")
+	}
+	e.opened++
+	stream := &fakeStream{onStop: onStop}
+	e.streams = append(e.streams, stream)
+	onData([]float64{0.5})
+	return stream, nil
+}
+
+// fakeClock lets tests move time forward past backoff delays.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestManager(enumerator *fakeEnumerator) (*DeviceManager, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	manager := NewDeviceManager(enumerator, MatchNames("OP-XY", "OP-Z"), NewRing(16))
+	manager.now = clock.Now
+	return manager, clock
+}
+
+func expectState(t *testing.T, manager *DeviceManager, want DeviceState) {
+	t.Helper()
+	if got := manager.Status().State; got != want {
+		t.Fatalf("Expected state %s, got %s", want, got)
+	}
+}
+
+func TestDeviceManagerIgnoresOtherDevices(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "1", Name: "Built-in Microphone"}}}
+	manager, _ := newTestManager(enumerator)
+
+	manager.Step()
+	expectState(t, manager, DeviceAbsent)
+	if enumerator.opened != 0 {
+		t.Errorf("Expected no device to be opened, opened %d", enumerator.opened)
+	}
+}
+
+func TestDeviceManagerStreamsAndSurvivesReenumeration(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "1", Name: "Mic"}, {ID: "2", Name: "OP-XY"}}}
+	manager, _ := newTestManager(enumerator)
+
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+	if manager.ring.Written() != 1 {
+		t.Errorf("Expected the stream to write into the ring")
+	}
+
+	// A fresh listing with the same ID must not reopen the device
+	enumerator.devices = []DeviceInfo{{ID: "2", Name: "OP-XY"}}
+	manager.Step()
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+	if enumerator.opened != 1 {
+		t.Errorf("Expected the device to be opened once, opened %d", enumerator.opened)
+	}
+}
+
+func TestDeviceManagerUnplug(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "2", Name: "OP-Z"}}}
+	manager, _ := newTestManager(enumerator)
+
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+
+	enumerator.devices = nil
+	manager.Step()
+	expectState(t, manager, DeviceAbsent)
+	if !enumerator.streams[0].closed {
+		t.Error("Expected the stream to be closed after unplugging")
+	}
+
+	enumerator.devices = []DeviceInfo{{ID: "3", Name: "OP-Z"}}
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+	if got := manager.Status().Device.ID; got != "3" {
+		t.Errorf("Expected the replugged device, got ID %s", got)
+	}
+}
+
+func TestDeviceManagerRetriesWithBackoff(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "2", Name: "OP-XY"}}, failures: 2}
+	manager, clock := newTestManager(enumerator)
+
+	manager.Step()
+	expectState(t, manager, DeviceFailed)
+	if manager.Status().Err == nil {
+		t.Error("Expected the open error to be reported")
+	}
+
+	// Nothing happens before the backoff expires
+	manager.Step()
+	expectState(t, manager, DeviceFailed)
+
+	clock.now = manager.Status().RetryAt
+	manager.Step()
+	expectState(t, manager, DeviceFailed)
+	if wait := manager.Status().RetryAt.Sub(clock.now); wait != 2*minBackoff {
+		t.Errorf("Expected the second retry after %s, got %s", 2*minBackoff, wait)
+	}
+
+	clock.now = manager.Status().RetryAt
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+}
+
+func TestDeviceManagerReconnectsAfterStop(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "2", Name: "OP-XY"}}}
+	manager, clock := newTestManager(enumerator)
+
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+
+	// The backend reports the stream stopped, e.g. a USB glitch
+	enumerator.streams[0].onStop()
+	manager.Step()
+	expectState(t, manager, DeviceReconnecting)
+
+	clock.now = manager.Status().RetryAt
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+	if enumerator.opened != 2 {
+		t.Errorf("Expected the device to be reopened, opened %d", enumerator.opened)
+	}
+
+	// A late stop callback from the old stream is ignored
+	enumerator.streams[0].onStop()
+	manager.Step()
+	expectState(t, manager, DeviceRunning)
+}
+
+func TestDeviceManagerGivesUpReconnecting(t *testing.T) {
+	enumerator := &fakeEnumerator{devices: []DeviceInfo{{ID: "2", Name: "OP-XY"}}}
+	manager, clock := newTestManager(enumerator)
+
+	manager.Step()
+	enumerator.streams[0].onStop()
+	enumerator.devices = nil
+	manager.Step()
+	expectState(t, manager, DeviceReconnecting)
+
+	for i := 0; i < maxReconnects; i++ {
+		clock.now = manager.Status().RetryAt
+		manager.Step()
+	}
+	expectState(t, manager, DeviceAbsent)
+}
+
+func TestBackoff(t *testing.T) {
+	if backoff(1) != minBackoff {
+		t.Errorf("Expected the first retry after %s, got %s", minBackoff, backoff(1))
+	}
+	if backoff(100) != maxBackoff {
+		t.Errorf("Expected retries to be capped at %s, got %s", maxBackoff, backoff(100))
+	}
+}
+
+func TestIsMonitor(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"Monitor of Built-in Audio Analog Stereo", "alsa_output.pci-0000_00_1f.3.analog-stereo.monitor", true},
+		{"Monitor of OP-Z", "", true},
+		{"Built-in Audio Analog Stereo", "alsa_input.pci-0000_00_1f.3.analog-stereo", false},
+		{"OP-XY", "alsa_input.usb-teenage_engineering_OP-XY", false},
+	} {
+		id := make([]byte, 256)
+		copy(id, tc.id)
+		if got := isMonitor(tc.name, id); got != tc.want {
+			t.Errorf("isMonitor(%q, %q) = %v, want %v", tc.name, tc.id, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultChirpSeconds = 10.0                  // Length of one chirp sweep unless given
+	generatorInterval   = 10 * time.Millisecond // How often a running generator writes audio
+)
+
+// Signal kinds understood by ParseSignal.
+const (
+	Sine   = "sine"
+	Square = "square"
+	Saw    = "saw"
+	White  = "white"
+	Pink   = "pink"
+	Chirp  = "chirp"
+	Drums  = "drums"
+)
+
+// Voice is one component of a generated signal.
+type Voice struct {
+	Kind      string
+	Frequency float64 // Hz for tones, start of the sweep for chirps, beats per minute for drums
+	End       float64 // End of the sweep in Hz for chirps
+	Period    float64 // Length of one chirp sweep in seconds
+	Gain      float64
+}
+
+// Generator synthesises test signals. For a given seed it always produces the
+// same samples, so analysis and visuals can be tested deterministically.
+type Generator struct {
+	voices     []Voice
+	sampleRate int
+	rng        *rand.Rand
+	position   int64
+	pink       [][7]float64 // Filter state per pink noise voice
+	phase      []float64    // Oscillator phase per voice, in cycles
+}
+
+// NewGenerator creates a generator mixing voices at sampleRate, seeding its
+// noise sources with seed.
+func NewGenerator(voices []Voice, sampleRate int, seed int64) *Generator {
+	return &Generator{
+		voices:     voices,
+		sampleRate: sampleRate,
+		rng:        rand.New(rand.NewSource(seed)),
+		pink:       make([][7]float64, len(voices)),
+		phase:      make([]float64, len(voices)),
+	}
+}
+
+// ParseSignal parses a signal description such as "sine:440+pink*0.2" into
+// voices. Each term separated by "+" is a kind with an optional argument
+// after ":" and an optional gain after "*":
+//
+//	sine:440, square:110, saw:55  tones at the given frequency in Hz
+//	white, pink                   noise
+//	chirp:20-8000/5               sweep from 20 Hz to 8 kHz every 5 seconds
+//	drums:120                     kick on the beat and hats between at 120 BPM
+func ParseSignal(description string) ([]Voice, error) {
+	var voices []Voice
+	for _, term := range strings.Split(description, "+") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		voice := Voice{Gain: 1}
+		if kind, gain, found := strings.Cut(term, "*"); found {
+			value, err := strconv.ParseFloat(gain, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gain in %q: %v", term, err)
+			}
+			voice.Gain = value
+			term = kind
+		}
+
+		kind, argument, _ := strings.Cut(term, ":")
+		voice.Kind = kind
+		switch kind {
+		case Sine, Square, Saw:
+			voice.Frequency = 440
+			if argument != "" {
+				value, err := strconv.ParseFloat(argument, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid frequency in %q: %v", term, err)
+				}
+				voice.Frequency = value
+			}
+		case White, Pink:
+		case Chirp:
+			voice.Frequency, voice.End, voice.Period = 20, 20000, defaultChirpSeconds
+			if argument != "" {
+				sweep, period, hasPeriod := strings.Cut(argument, "/")
+				low, high, found := strings.Cut(sweep, "-")
+				if !found {
+					return nil, fmt.Errorf("chirp range in %q must look like 20-8000", term)
+				}
+				var err error
+				if voice.Frequency, err = strconv.ParseFloat(low, 64); err != nil {
+					return nil, fmt.Errorf("invalid chirp start in %q: %v", term, err)
+				}
+				if voice.End, err = strconv.ParseFloat(high, 64); err != nil {
+					return nil, fmt.Errorf("invalid chirp end in %q: %v", term, err)
+				}
+				if hasPeriod {
+					if voice.Period, err = strconv.ParseFloat(period, 64); err != nil || voice.Period <= 0 {
+						return nil, fmt.Errorf("invalid chirp period in %q", term)
+					}
+				}
+			}
+			if voice.Frequency <= 0 || voice.End <= 0 {
+				return nil, fmt.Errorf("chirp frequencies in %q must be positive", term)
+			}
+		case Drums:
+			voice.Frequency = 120
+			if argument != "" {
+				value, err := strconv.ParseFloat(argument, 64)
+				if err != nil || value <= 0 {
+					return nil, fmt.Errorf("invalid tempo in %q", term)
+				}
+				voice.Frequency = value
+			}
+		default:
+			return nil, fmt.Errorf("unknown signal %q", kind)
+		}
+		voices = append(voices, voice)
+	}
+
+	if len(voices) == 0 {
+		return nil, fmt.Errorf("empty signal description")
+	}
+	return voices, nil
+}
+
+// Read fills samples with the next stretch of the signal.
+func (g *Generator) Read(samples []float64) {
+	for i := range samples {
+		t := float64(g.position) / float64(g.sampleRate)
+		mix := 0.0
+		for v, voice := range g.voices {
+			mix += voice.Gain * g.voiceSample(v, voice, t)
+		}
+		// Scale the mix down so several voices at full gain still fit
+		samples[i] = math.Max(-1, math.Min(1, mix/math.Max(1, float64(len(g.voices))/2)))
+		g.position++
+	}
+}
+
+// Run writes the signal into ring in real time until ctx is cancelled.
+func (g *Generator) Run(ctx context.Context, ring *Ring) {
+	ticker := time.NewTicker(generatorInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	var produced int64
+	buffer := make([]float64, 0, g.sampleRate)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Catch up with the wall clock rather than counting ticks, so the pitch never drifts
+			due := int64(time.Since(start).Seconds() * float64(g.sampleRate))
+			count := int(min(due-produced, int64(cap(buffer))))
+			if count <= 0 {
+				continue
+			}
+			buffer = buffer[:count]
+			g.Read(buffer)
+			ring.Write(buffer)
+			produced = due
+		}
+	}
+}
+
+func (g *Generator) voiceSample(index int, voice Voice, t float64) float64 {
+	switch voice.Kind {
+	case Sine:
+		return math.Sin(2 * math.Pi * g.advance(index, voice.Frequency))
+	case Square:
+		if g.advance(index, voice.Frequency) < 0.5 {
+			return 1
+		}
+		return -1
+	case Saw:
+		return 2*g.advance(index, voice.Frequency) - 1
+	case White:
+		return g.rng.Float64()*2 - 1
+	case Pink:
+		return g.pinkNoise(index)
+	case Chirp:
+		// Exponential sweep, so each octave takes the same time
+		progress := math.Mod(t, voice.Period) / voice.Period
+		frequency := voice.Frequency * math.Pow(voice.End/voice.Frequency, progress)
+		return math.Sin(2 * math.Pi * g.advance(index, frequency))
+	case Drums:
+		return g.drums(voice.Frequency, t)
+	}
+	return 0
+}
+
+// advance moves the oscillator of a voice on by one sample and returns its
+// phase in cycles, between 0 and 1.
+func (g *Generator) advance(index int, frequency float64) float64 {
+	phase := g.phase[index]
+	g.phase[index] = math.Mod(phase+frequency/float64(g.sampleRate), 1)
+	return phase
+}
+
+// pinkNoise filters white noise to fall off at 3 dB per octave, using Paul
+// Kellet's economy filter.
+func (g *Generator) pinkNoise(index int) float64 {
+	white := g.rng.Float64()*2 - 1
+	b := &g.pink[index]
+	b[0] = 0.99886*b[0] + white*0.0555179
+	b[1] = 0.99332*b[1] + white*0.0750759
+	b[2] = 0.96900*b[2] + white*0.1538520
+	b[3] = 0.86650*b[3] + white*0.3104856
+	b[4] = 0.55000*b[4] + white*0.5329522
+	b[5] = -0.7616*b[5] - white*0.0168980
+	pink := b[0] + b[1] + b[2] + b[3] + b[4] + b[5] + b[6] + white*0.5362
+	b[6] = white * 0.115926
+	return pink * 0.11
+}
+
+// drums returns a kick on every beat and a hat on every off-beat.
+func (g *Generator) drums(bpm, t float64) float64 {
+	beat := 60 / bpm
+	sinceBeat := math.Mod(t, beat)
+
+	// Kick: a sine dropping from 150 Hz to 50 Hz with a fast decay
+	kick := 0.0
+	if sinceBeat < 0.3 {
+		frequency := 50 + 100*math.Exp(-sinceBeat*30)
+		kick = math.Sin(2*math.Pi*frequency*sinceBeat) * math.Exp(-sinceBeat*12)
+	}
+
+	// Hat: a short burst of noise halfway between beats
+	hat := 0.0
+	if sinceHat := sinceBeat - beat/2; sinceHat >= 0 && sinceHat < 0.05 {
+		hat = (g.rng.Float64()*2 - 1) * 0.5 * math.Exp(-sinceHat*80)
+	}
+	return kick + hat
+}
@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/idroz/mezmer/analysis"
+)
+
+func TestParseSignal(t *testing.T) {
+	voices, err := ParseSignal("sine:220 + pink*0.2 + chirp:20-8000/5 + drums:90")
+	if err != nil {
+		t.Fatalf("ParseSignal failed: %v", err)
+	}
+
+	want := []Voice{
+		{Kind: Sine, Frequency: 220, Gain: 1},
+		{Kind: Pink, Gain: 0.2},
+		{Kind: Chirp, Frequency: 20, End: 8000, Period: 5, Gain: 1},
+		{Kind: Drums, Frequency: 90, Gain: 1},
+	}
+	if len(voices) != len(want) {
+		t.Fatalf("ParseSignal made %d voices, want %d", len(voices), len(want))
+	}
+	for i := range want {
+		if voices[i] != want[i] {
+			t.Errorf("Voice %d is %+v, want %+v", i, voices[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "triangle:440", "sine:loud", "chirp:20", "drums:0", "saw*much"} {
+		if _, err := ParseSignal(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	voices, err := ParseSignal("saw:110+white*0.3+pink*0.3+drums:128")
+	if err != nil {
+		t.Fatalf("ParseSignal failed: %v", err)
+	}
+
+	first := make([]float64, 4096)
+	second := make([]float64, 4096)
+	NewGenerator(voices, 44100, 7).Read(first)
+	NewGenerator(voices, 44100, 7).Read(second)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Sample %d is %v on the second run, want %v", i, second[i], first[i])
+		}
+	}
+}
+
+func TestGeneratorSinePitch(t *testing.T) {
+	generator := NewGenerator([]Voice{{Kind: Sine, Frequency: 440, Gain: 1}}, 44100, 1)
+	samples := make([]float64, 1024)
+	generator.Read(samples)
+
+	pitch := analysis.DetectPitch(samples, 44100)
+	if math.Abs(pitch.Frequency-440) > 1 {
+		t.Errorf("Detected %.2f Hz, want 440", pitch.Frequency)
+	}
+	if pitch.Note.Name != "A" || pitch.Note.Octave != 4 {
+		t.Errorf("Detected %s, want A4", pitch.Note)
+	}
+}
@@ -0,0 +1,157 @@
+package audio
+
+import (
+	"math"
+	"sync"
+)
+
+const (
+	maxReorder    = 8     // Out-of-order packets held before a missing one is given up on
+	maxRewind     = 64    // Packets a sequence number can fall behind before the sender is taken to have restarted
+	maxDrift      = 0.005 // Largest playback rate correction, as a fraction
+	fillSmoothing = 0.999 // Per-sample smoothing of the measured buffer fill
+	overflow      = 1.0   // Seconds of audio held beyond the target before the oldest is dropped
+)
+
+// JitterBuffer reorders packets by sequence number and plays their samples
+// out at a steady rate, nudging the playback rate to keep its fill near the
+// target so a sender whose clock runs slightly fast or slow never over- or
+// underruns.
+type JitterBuffer struct {
+	mu        sync.Mutex
+	target    float64 // Samples to hold before playing, and to hover around
+	baseRatio float64 // Input samples consumed per output sample, from the two sample rates
+	pending   map[uint16][]float64
+	source    uint32 // Stream the sequence numbers belong to, the SSRC for RTP
+	nextSeq   uint16
+	started   bool // Whether source and nextSeq are known
+	buffered  []float64
+	limit     int     // Most samples buffered before the oldest are dropped
+	phase     float64 // Fractional read position into buffered
+	fill      float64 // Smoothed fill, for drift compensation
+	playing   bool    // False while prefilling after start or an underrun
+	underruns int
+}
+
+// NewJitterBuffer creates a buffer converting from inputRate to outputRate
+// that holds target samples (at inputRate) before playing.
+func NewJitterBuffer(target int, inputRate, outputRate int) *JitterBuffer {
+	return &JitterBuffer{
+		target:    float64(target),
+		baseRatio: float64(inputRate) / float64(outputRate),
+		pending:   make(map[uint16][]float64),
+		limit:     target + int(overflow*float64(inputRate)),
+		fill:      float64(target),
+	}
+}
+
+// Push adds the samples of a packet with the given sequence number from the
+// given source. A new source, or a sequence number far behind the current
+// one, means the sender restarted, so the buffer follows it from there.
+func (j *JitterBuffer) Push(source uint32, seq uint16, samples []float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.started || source != j.source || int16(seq-j.nextSeq) < -maxRewind {
+		j.source = source
+		j.nextSeq = seq
+		j.started = true
+		clear(j.pending)
+	}
+	// Drop packets that arrive after we have moved past them
+	if int16(seq-j.nextSeq) < 0 {
+		return
+	}
+	j.pending[seq] = samples
+
+	for {
+		if next, ok := j.pending[j.nextSeq]; ok {
+			delete(j.pending, j.nextSeq)
+			j.add(next)
+			j.nextSeq++
+			continue
+		}
+		if len(j.pending) <= maxReorder {
+			return
+		}
+		// Give up on the missing packet and continue from the oldest one held
+		oldest, distance := j.nextSeq, math.MaxInt
+		for seq := range j.pending {
+			if d := int(seq - j.nextSeq); d < distance {
+				oldest, distance = seq, d
+			}
+		}
+		j.nextSeq = oldest
+	}
+}
+
+// Append adds samples that carry no sequence number, in arrival order.
+func (j *JitterBuffer) Append(samples []float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.add(samples)
+}
+
+// add buffers samples, dropping the oldest beyond the limit so a sender that
+// runs far ahead can't grow the buffer without bound.
+func (j *JitterBuffer) add(samples []float64) {
+	j.buffered = append(j.buffered, samples...)
+	if excess := len(j.buffered) - j.limit; excess > 0 {
+		j.buffered = append(j.buffered[:0], j.buffered[excess:]...)
+		j.phase = math.Max(0, j.phase-float64(excess))
+	}
+}
+
+// Pull fills dst with the next samples at the output rate. While prefilling
+// or after an underrun it plays silence.
+func (j *JitterBuffer) Pull(dst []float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for i := range dst {
+		available := float64(len(j.buffered)) - j.phase
+		if !j.playing {
+			if available < j.target {
+				dst[i] = 0
+				continue
+			}
+			j.playing = true
+			j.fill = available
+		}
+		if available < 2 {
+			j.playing = false
+			j.underruns++
+			dst[i] = 0
+			continue
+		}
+
+		// Linear interpolation between neighbouring input samples
+		index := int(j.phase)
+		fraction := j.phase - float64(index)
+		dst[i] = j.buffered[index]*(1-fraction) + j.buffered[index+1]*fraction
+
+		// Play slightly faster when too full and slower when too empty
+		j.fill = fillSmoothing*j.fill + (1-fillSmoothing)*available
+		correction := math.Max(-maxDrift, math.Min(maxDrift, maxDrift*(j.fill-j.target)/j.target))
+		j.phase += j.baseRatio * (1 + correction)
+	}
+
+	// Drop the samples we have moved past
+	consumed := int(j.phase)
+	j.buffered = append(j.buffered[:0], j.buffered[consumed:]...)
+	j.phase -= float64(consumed)
+}
+
+// Fill returns the number of input samples waiting to be played.
+func (j *JitterBuffer) Fill() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.buffered)
+}
+
+// Underruns returns how many times the buffer ran dry.
+func (j *JitterBuffer) Underruns() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.underruns
+}
@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/gen2brain/malgo"
+)
+
+// loopbackID identifies the pseudo device that captures system playback
+// through miniaudio's loopback mode.
+const loopbackID = "loopback"
+
+// MalgoEnumerator lists and opens capture devices through miniaudio.
+type MalgoEnumerator struct {
+	ctx        *malgo.AllocatedContext
+	sampleRate int
+	system     bool // List only sources that capture system playback
+
+	mu  sync.Mutex
+	ids map[string]malgo.DeviceID // Native IDs of the devices seen so far
+}
+
+// NewMalgoEnumerator creates an enumerator capturing mono audio at sampleRate.
+func NewMalgoEnumerator(ctx *malgo.AllocatedContext, sampleRate int) *MalgoEnumerator {
+	return &MalgoEnumerator{
+		ctx:        ctx,
+		sampleRate: sampleRate,
+		ids:        make(map[string]malgo.DeviceID),
+	}
+}
+
+// NewMalgoSystemEnumerator creates an enumerator for whatever the machine is
+// playing. WASAPI captures the default output through loopback; elsewhere
+// the PulseAudio and PipeWire monitor sources are listed instead.
+func NewMalgoSystemEnumerator(ctx *malgo.AllocatedContext, sampleRate int) *MalgoEnumerator {
+	e := NewMalgoEnumerator(ctx, sampleRate)
+	e.system = true
+	return e
+}
+
+func (e *MalgoEnumerator) Devices() ([]DeviceInfo, error) {
+	// Only WASAPI implements loopback capture in miniaudio
+	if e.system && runtime.GOOS == "windows" {
+		return []DeviceInfo{{ID: loopbackID, Name: "System output"}}, nil
+	}
+
+	devices, err := e.ctx.Devices(malgo.Capture)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	infos := make([]DeviceInfo, 0, len(devices))
+	for _, device := range devices {
+		if e.system && !isMonitor(device.Name(), device.ID[:]) {
+			continue
+		}
+		id := device.ID.String()
+		e.ids[id] = device.ID
+		infos = append(infos, DeviceInfo{ID: id, Name: device.Name()})
+	}
+	return infos, nil
+}
+
+func (e *MalgoEnumerator) Open(device DeviceInfo, onData func([]float64), onStop func()) (Stream, error) {
+	var deviceConfig malgo.DeviceConfig
+	if device.ID == loopbackID {
+		// Loopback records the default playback device with the capture settings
+		deviceConfig = malgo.DefaultDeviceConfig(malgo.Loopback)
+	} else {
+		e.mu.Lock()
+		id, ok := e.ids[device.ID]
+		e.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown device %s", device.Name)
+		}
+		deviceConfig = malgo.DefaultDeviceConfig(malgo.Capture)
+		deviceConfig.Capture.DeviceID = id.Pointer()
+	}
+	deviceConfig.Capture.Format = malgo.FormatS16
+	deviceConfig.Capture.Channels = 1
+	deviceConfig.SampleRate = uint32(e.sampleRate)
+
+	var samples []float64
+	deviceCallbacks := malgo.DeviceCallbacks{
+		Data: func(_, inputSamples []byte, frameCount uint32) {
+			samples = decodeS16(samples[:0], inputSamples, int(frameCount))
+			onData(samples)
+		},
+		Stop: onStop,
+	}
+
+	captureDevice, err := malgo.InitDevice(e.ctx.Context, deviceConfig, deviceCallbacks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s: %v", device.Name, err)
+	}
+	if err := captureDevice.Start(); err != nil {
+		captureDevice.Uninit()
+		return nil, fmt.Errorf("failed to start %s: %v", device.Name, err)
+	}
+	return malgoStream{captureDevice}, nil
+}
+
+// isMonitor reports whether a capture device records a playback device.
+// PulseAudio, and PipeWire through its Pulse server, name monitor sources
+// "<sink>.monitor" and describe them as "Monitor of <sink>".
+func isMonitor(name string, id []byte) bool {
+	source := string(bytes.TrimRight(id, "\x00"))
+	return strings.HasSuffix(source, ".monitor") || strings.HasPrefix(name, "Monitor of ")
+}
+
+type malgoStream struct {
+	device *malgo.Device
+}
+
+func (s malgoStream) Close() {
+	s.device.Uninit()
+}
+
+// decodeS16 appends count little-endian signed 16-bit samples from data to
+// samples as floats between -1 and 1.
+func decodeS16(samples []float64, data []byte, count int) []float64 {
+	for i := 0; i < count && 2*i+1 < len(data); i++ {
+		sample := int16(data[2*i]) | int16(data[2*i+1])<<8
+		samples = append(samples, float64(sample)/32768.0)
+	}
+	return samples
+}
@@ -0,0 +1,171 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	rtpVersion      = 2
+	rtpHeaderSize   = 12
+	maxPacketSize   = 65536
+	networkInterval = 10 * time.Millisecond // How often buffered audio is played into the ring
+)
+
+// Network protocols understood by ListenNetwork.
+const (
+	UDP = "udp" // Bare PCM datagrams, played in arrival order
+	RTP = "rtp" // RTP with L16 or L24 payloads, reordered by sequence number
+)
+
+// NetworkConfig describes an incoming network audio stream.
+type NetworkConfig struct {
+	Protocol   string // UDP or RTP
+	Address    string // Local address to listen on, e.g. ":5004"
+	Format     string // Sample format, see DecodePCM; RTP uses S16BE (L16) or S24BE (L24)
+	Channels   int
+	SampleRate int           // Sample rate of the stream
+	Latency    time.Duration // Audio held in the jitter buffer
+}
+
+// NetworkReceiver plays audio received over UDP or RTP into a Ring, as if it
+// came from a local capture device.
+type NetworkReceiver struct {
+	config     NetworkConfig
+	conn       net.PacketConn
+	buffer     *JitterBuffer
+	outputRate int
+}
+
+// ListenNetwork opens the socket for config, delivering audio at outputRate.
+func ListenNetwork(config NetworkConfig, outputRate int) (*NetworkReceiver, error) {
+	if config.Protocol != UDP && config.Protocol != RTP {
+		return nil, fmt.Errorf("unknown network protocol %q", config.Protocol)
+	}
+	config.Format = canonicalFormat(config.Format)
+	if _, err := SampleSize(config.Format); err != nil {
+		return nil, err
+	}
+	if config.Channels < 1 || config.SampleRate < 1 {
+		return nil, fmt.Errorf("invalid stream of %d channels at %d Hz", config.Channels, config.SampleRate)
+	}
+
+	conn, err := net.ListenPacket("udp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	target := int(config.Latency.Seconds() * float64(config.SampleRate))
+	return &NetworkReceiver{
+		config:     config,
+		conn:       conn,
+		buffer:     NewJitterBuffer(max(1, target), config.SampleRate, outputRate),
+		outputRate: outputRate,
+	}, nil
+}
+
+// Addr returns the local address the receiver listens on.
+func (r *NetworkReceiver) Addr() net.Addr {
+	return r.conn.LocalAddr()
+}
+
+// Buffer returns the receiver's jitter buffer.
+func (r *NetworkReceiver) Buffer() *JitterBuffer {
+	return r.buffer
+}
+
+// Run receives packets and plays them into ring in real time until ctx is
+// cancelled, then closes the socket.
+func (r *NetworkReceiver) Run(ctx context.Context, ring *Ring) {
+	go func() {
+		<-ctx.Done()
+		r.conn.Close()
+	}()
+	go r.receive()
+	playOut(ctx, wallClock{}, r.buffer, ring, r.outputRate)
+}
+
+// receive reads packets into the jitter buffer until the socket is closed.
+func (r *NetworkReceiver) receive() {
+	packet := make([]byte, maxPacketSize)
+	for {
+		n, _, err := r.conn.ReadFrom(packet)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Network input stopped: %v", err)
+			}
+			return
+		}
+		if err := r.handle(packet[:n]); err != nil {
+			log.Printf("Dropped packet: %v", err)
+		}
+	}
+}
+
+// handle decodes one datagram into the jitter buffer.
+func (r *NetworkReceiver) handle(packet []byte) error {
+	if r.config.Protocol == UDP {
+		samples, _, err := DecodePCM(nil, packet, r.config.Format, r.config.Channels)
+		if err != nil {
+			return err
+		}
+		r.buffer.Append(samples)
+		return nil
+	}
+
+	header, payload, err := ParseRTP(packet)
+	if err != nil {
+		return err
+	}
+	samples, _, err := DecodePCM(nil, payload, r.config.Format, r.config.Channels)
+	if err != nil {
+		return err
+	}
+	r.buffer.Push(header.SSRC, header.Sequence, samples)
+	return nil
+}
+
+// RTPHeader holds the fields of an RTP header that playback depends on.
+type RTPHeader struct {
+	Sequence uint16
+	SSRC     uint32 // Identifies the stream, and changes when the sender restarts it
+}
+
+// ParseRTP returns the header and payload of an RTP packet.
+func ParseRTP(packet []byte) (RTPHeader, []byte, error) {
+	if len(packet) < rtpHeaderSize {
+		return RTPHeader{}, nil, fmt.Errorf("RTP packet of %d bytes is too short", len(packet))
+	}
+	if version := packet[0] >> 6; version != rtpVersion {
+		return RTPHeader{}, nil, fmt.Errorf("unsupported RTP version %d", version)
+	}
+
+	padding := packet[0]&0x20 != 0
+	extension := packet[0]&0x10 != 0
+	csrcCount := int(packet[0] & 0x0f)
+	header := RTPHeader{
+		Sequence: binary.BigEndian.Uint16(packet[2:4]),
+		SSRC:     binary.BigEndian.Uint32(packet[8:12]),
+	}
+
+	offset := rtpHeaderSize + 4*csrcCount
+	if extension {
+		if len(packet) < offset+4 {
+			return RTPHeader{}, nil, fmt.Errorf("truncated RTP header extension")
+		}
+		offset += 4 + 4*int(binary.BigEndian.Uint16(packet[offset+2:offset+4]))
+	}
+	end := len(packet)
+	if padding && end > 0 {
+		end -= int(packet[end-1])
+	}
+	if offset > end {
+		return RTPHeader{}, nil, fmt.Errorf("RTP header longer than packet")
+	}
+	return header, packet[offset:end], nil
+}
@@ -0,0 +1,201 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+// rtpPacket builds an RTP packet carrying samples as L16.
+func rtpPacket(seq uint16, timestamp uint32, samples []float64) []byte {
+	packet := make([]byte, rtpHeaderSize+2*len(samples))
+	packet[0] = rtpVersion << 6
+	packet[1] = 96 // Dynamic payload type
+	binary.BigEndian.PutUint16(packet[2:], seq)
+	binary.BigEndian.PutUint32(packet[4:], timestamp)
+	binary.BigEndian.PutUint32(packet[8:], 0x1234)
+	for i, sample := range samples {
+		binary.BigEndian.PutUint16(packet[rtpHeaderSize+2*i:], uint16(int16(sample*32767)))
+	}
+	return packet
+}
+
+// ramp returns count samples counting up from start in steps of 1/1024.
+func ramp(start, count int) []float64 {
+	samples := make([]float64, count)
+	for i := range samples {
+		samples[i] = float64(start+i) / 1024
+	}
+	return samples
+}
+
+func TestNetworkReceiverReordersRTP(t *testing.T) {
+	receiver, err := ListenNetwork(NetworkConfig{
+		Protocol:   RTP,
+		Address:    "127.0.0.1:0",
+		Format:     L16,
+		Channels:   1,
+		SampleRate: 8000,
+		Latency:    time.Millisecond,
+	}, 8000)
+	if err != nil {
+		t.Fatalf("ListenNetwork failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		receiver.conn.Close()
+	}()
+	go receiver.receive()
+
+	// A local sender stands in for the mixing machine
+	sender, err := net.Dial("udp", receiver.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer sender.Close()
+	for _, seq := range []uint16{65534, 0, 65535, 1} {
+		index := int(seq+2) * 16 // Continuous ramp across the sequence wrap
+		if _, err := sender.Write(rtpPacket(seq, uint32(index), ramp(index, 16))); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		// Keep the packets in the order sent over loopback
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for receiver.Buffer().Fill() < 64 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if fill := receiver.Buffer().Fill(); fill != 64 {
+		t.Fatalf("Expected 64 samples buffered, got %d", fill)
+	}
+
+	output := make([]float64, 60)
+	receiver.Buffer().Pull(output)
+	for i := 1; i < len(output); i++ {
+		if step := output[i] - output[i-1]; math.Abs(step-1.0/1024) > 1e-4 {
+			t.Fatalf("Samples out of order at %d: %v then %v", i, output[i-1], output[i])
+		}
+	}
+}
+
+func TestJitterBufferSkipsLostPackets(t *testing.T) {
+	buffer := NewJitterBuffer(1, 48000, 48000)
+	buffer.Push(1, 10, []float64{1})
+	// Packet 11 is lost, later ones pile up until the buffer gives up on it
+	for seq := uint16(12); seq < 12+maxReorder+1; seq++ {
+		buffer.Push(1, seq, []float64{float64(seq)})
+	}
+	if fill := buffer.Fill(); fill != maxReorder+2 {
+		t.Errorf("Expected %d samples after skipping the lost packet, got %d", maxReorder+2, fill)
+	}
+
+	// A very late packet is dropped
+	buffer.Push(1, 11, []float64{11})
+	if fill := buffer.Fill(); fill != maxReorder+2 {
+		t.Errorf("Expected the late packet to be dropped, fill is %d", fill)
+	}
+}
+
+func TestJitterBufferFollowsRestarts(t *testing.T) {
+	tests := []struct {
+		name   string
+		source uint32
+		seq    uint16
+	}{
+		{"sequence jumps back", 1, 100},
+		{"new source", 2, 995},
+	}
+	for _, tt := range tests {
+		buffer := NewJitterBuffer(1, 48000, 48000)
+		for seq := uint16(990); seq < 1000; seq++ {
+			buffer.Push(1, seq, []float64{1})
+		}
+		// The sender restarts, and everything it sends from then on plays
+		for i := uint16(0); i < 3; i++ {
+			buffer.Push(tt.source, tt.seq+i, []float64{2})
+		}
+		if fill := buffer.Fill(); fill != 13 {
+			t.Errorf("%s: expected 13 samples after the restart, got %d", tt.name, fill)
+		}
+	}
+}
+
+func TestJitterBufferDropsOldestWhenFull(t *testing.T) {
+	buffer := NewJitterBuffer(10, 100, 100)
+	// A sender far ahead of playback fills the buffer past its limit
+	for i := 0; i < 300; i++ {
+		buffer.Append([]float64{float64(i)})
+	}
+	if fill := buffer.Fill(); fill != buffer.limit {
+		t.Fatalf("Expected the fill to stop at %d, got %d", buffer.limit, fill)
+	}
+
+	output := make([]float64, 1)
+	buffer.Pull(output)
+	if want := float64(300 - buffer.limit); output[0] != want {
+		t.Errorf("Expected playback to resume with the newest samples from %v, got %v", want, output[0])
+	}
+}
+
+func TestJitterBufferCompensatesDrift(t *testing.T) {
+	const target = 480
+	buffer := NewJitterBuffer(target, 48000, 48000)
+	output := make([]float64, 480)
+
+	// The sender's clock runs 0.2% fast, so it delivers 481 samples per 480 played
+	sent := 0
+	for block := 0; block < 2000; block++ {
+		packet := make([]float64, 481)
+		for i := range packet {
+			packet[i] = math.Sin(float64(sent+i) / 10)
+		}
+		sent += len(packet)
+		buffer.Append(packet)
+		buffer.Pull(output)
+	}
+
+	if fill := buffer.Fill(); fill > 3*target {
+		t.Errorf("Expected drift compensation to hold the fill near %d, got %d", target, fill)
+	}
+	if underruns := buffer.Underruns(); underruns != 0 {
+		t.Errorf("Expected no underruns, got %d", underruns)
+	}
+}
+
+func TestParseRTPRejectsGarbage(t *testing.T) {
+	if _, _, err := ParseRTP([]byte{1, 2, 3}); err == nil {
+		t.Error("Expected a short packet to be rejected")
+	}
+	packet := rtpPacket(1, 0, ramp(0, 4))
+	packet[0] = 1 << 6
+	if _, _, err := ParseRTP(packet); err == nil {
+		t.Error("Expected RTP version 1 to be rejected")
+	}
+}
+
+func TestDecodePCM(t *testing.T) {
+	// Stereo s16le frame of full-scale negative and zero mixes to -0.5
+	samples, consumed, err := DecodePCM(nil, []byte{0x00, 0x80, 0x00, 0x00, 0xff}, S16LE, 2)
+	if err != nil {
+		t.Fatalf("DecodePCM failed: %v", err)
+	}
+	if consumed != 4 || len(samples) != 1 || samples[0] != -0.5 {
+		t.Errorf("Expected one sample of -0.5 from 4 bytes, got %v from %d", samples, consumed)
+	}
+
+	// L24 half scale
+	samples, _, err = DecodePCM(nil, []byte{0x40, 0x00, 0x00}, L24, 1)
+	if err != nil || len(samples) != 1 || samples[0] != 0.5 {
+		t.Errorf("Expected 0.5 from L24, got %v (%v)", samples, err)
+	}
+
+	if _, _, err := DecodePCM(nil, nil, "u8", 1); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,84 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PCM sample formats understood by DecodePCM.
+const (
+	S16LE = "s16le" // Signed 16-bit little-endian, as produced by ffmpeg -f s16le
+	S16BE = "s16be" // Signed 16-bit big-endian, the RTP L16 payload
+	S24LE = "s24le" // Signed 24-bit little-endian
+	S24BE = "s24be" // Signed 24-bit big-endian, the RTP L24 payload
+	F32LE = "f32le" // 32-bit float little-endian
+	L16   = "l16"   // RTP name for S16BE
+	L24   = "l24"   // RTP name for S24BE
+)
+
+// SampleSize returns the number of bytes per sample of format.
+func SampleSize(format string) (int, error) {
+	switch canonicalFormat(format) {
+	case S16LE, S16BE:
+		return 2, nil
+	case S24LE, S24BE:
+		return 3, nil
+	case F32LE:
+		return 4, nil
+	}
+	return 0, fmt.Errorf("unknown sample format %q", format)
+}
+
+// canonicalFormat maps the RTP payload names onto the sample formats they use.
+func canonicalFormat(format string) string {
+	switch format {
+	case L16:
+		return S16BE
+	case L24:
+		return S24BE
+	}
+	return format
+}
+
+// DecodePCM appends the interleaved frames in data to samples, mixing
+// channels down to mono. Trailing bytes that do not make a whole frame are
+// ignored; the number of bytes consumed is returned.
+func DecodePCM(samples []float64, data []byte, format string, channels int) ([]float64, int, error) {
+	format = canonicalFormat(format)
+	size, err := SampleSize(format)
+	if err != nil {
+		return samples, 0, err
+	}
+	if channels < 1 {
+		return samples, 0, fmt.Errorf("invalid channel count %d", channels)
+	}
+
+	frameSize := size * channels
+	frames := len(data) / frameSize
+	for frame := 0; frame < frames; frame++ {
+		mix := 0.0
+		for channel := 0; channel < channels; channel++ {
+			offset := frame*frameSize + channel*size
+			mix += decodeSample(data[offset:offset+size], format)
+		}
+		samples = append(samples, mix/float64(channels))
+	}
+	return samples, frames * frameSize, nil
+}
+
+func decodeSample(b []byte, format string) float64 {
+	switch format {
+	case S16LE:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / 32768
+	case S16BE:
+		return float64(int16(binary.BigEndian.Uint16(b))) / 32768
+	case S24LE:
+		return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / 8388608
+	case S24BE:
+		return float64(int32(uint32(b[2])<<8|uint32(b[1])<<16|uint32(b[0])<<24)>>8) / 8388608
+	case F32LE:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	return 0
+}
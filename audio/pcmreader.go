@@ -0,0 +1,180 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	pcmReadFrames = 1024                 // Frames read from the stream at a time
+	pcmWait       = 5 * time.Millisecond // Pause while the buffer is full enough
+)
+
+// PCMReader plays raw interleaved PCM from stdin, a named pipe or a file into
+// a Ring in real time. It reads no faster than it plays, so a producer such
+// as ffmpeg is held back by the pipe rather than racing ahead.
+type PCMReader struct {
+	path     string // "-" for stdin
+	format   string
+	channels int
+	buffer   *JitterBuffer
+	target   int
+	output   int
+	clock    clock
+}
+
+// NewPCMReader creates a reader for path, or stdin when path is "-", holding
+// latency worth of audio.
+func NewPCMReader(path, format string, channels, sampleRate, outputRate int, latency time.Duration) (*PCMReader, error) {
+	if _, err := SampleSize(format); err != nil {
+		return nil, err
+	}
+	if channels < 1 || sampleRate < 1 {
+		return nil, fmt.Errorf("invalid stream of %d channels at %d Hz", channels, sampleRate)
+	}
+	target := max(1, int(latency.Seconds()*float64(sampleRate)))
+	return &PCMReader{
+		path:     path,
+		format:   format,
+		channels: channels,
+		buffer:   NewJitterBuffer(target, sampleRate, outputRate),
+		target:   target,
+		output:   outputRate,
+		clock:    wallClock{},
+	}, nil
+}
+
+// Run plays the stream into ring until ctx is cancelled. Named pipes are
+// reopened when their writer goes away, so a new producer can connect;
+// stdin and regular files play once.
+func (p *PCMReader) Run(ctx context.Context, ring *Ring) {
+	go p.read(ctx)
+	playOut(ctx, p.clock, p.buffer, ring, p.output)
+}
+
+func (p *PCMReader) read(ctx context.Context) {
+	for {
+		input, reopen, err := p.open()
+		if err != nil {
+			log.Printf("Failed to open %s: %v", p.path, err)
+			return
+		}
+
+		err = p.copy(ctx, input)
+		if input != os.Stdin {
+			input.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to read %s: %v", p.path, err)
+			return
+		}
+		if !reopen {
+			log.Printf("End of input %s", p.path)
+			return
+		}
+	}
+}
+
+// open returns the stream to read and whether to reopen it once it ends.
+func (p *PCMReader) open() (*os.File, bool, error) {
+	if p.path == "-" {
+		return os.Stdin, false, nil
+	}
+
+	// Opening a named pipe blocks until a writer connects
+	input, err := os.Open(p.path)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := input.Stat()
+	if err != nil {
+		input.Close()
+		return nil, false, err
+	}
+	return input, info.Mode()&os.ModeNamedPipe != 0, nil
+}
+
+// copy decodes input into the buffer until it ends or ctx is cancelled.
+func (p *PCMReader) copy(ctx context.Context, input io.Reader) error {
+	size, _ := SampleSize(p.format)
+	chunk := make([]byte, pcmReadFrames*size*p.channels)
+	var pending []byte
+	var samples []float64
+	for ctx.Err() == nil {
+		if p.buffer.Fill() >= p.target {
+			time.Sleep(pcmWait)
+			continue
+		}
+
+		n, err := input.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		var consumed int
+		var decodeErr error
+		samples, consumed, decodeErr = DecodePCM(samples[:0], pending, p.format, p.channels)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		pending = append(pending[:0], pending[consumed:]...)
+		p.buffer.Append(samples)
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clock paces playback. The wall clock plays in real time; tests advance
+// one by hand.
+type clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) (<-chan time.Time, func())
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time { return time.Now() }
+
+func (wallClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+// playOut pulls from buffer into ring at outputRate, as timed by clk, until
+// ctx is cancelled.
+func playOut(ctx context.Context, clk clock, buffer *JitterBuffer, ring *Ring, outputRate int) {
+	ticks, stop := clk.NewTicker(networkInterval)
+	defer stop()
+
+	start := clk.Now()
+	var produced int64
+	output := make([]float64, 0, outputRate)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			// Catch up with the wall clock rather than counting ticks
+			due := int64(clk.Now().Sub(start).Seconds() * float64(outputRate))
+			count := int(min(due-produced, int64(cap(output))))
+			if count <= 0 {
+				continue
+			}
+			output = output[:count]
+			buffer.Pull(output)
+			ring.Write(output)
+			produced = due
+		}
+	}
+}
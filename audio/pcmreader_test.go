@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a clock that only moves when advanced.
+type manualClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	ticks chan time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	return c.ticks, func() {}
+}
+
+// advance moves the clock on by d and ticks.
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	c.mu.Unlock()
+	c.ticks <- now
+}
+
+// waitFor waits until done reports true.
+func waitFor(done func() bool) {
+	for !done() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPCMReaderPlaysFileInRealTime(t *testing.T) {
+	// Half a second of a constant level as 16-bit stereo
+	const frames = 22050
+	data := make([]byte, frames*4)
+	for i := 0; i < frames*2; i++ {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(16384)))
+	}
+	path := filepath.Join(t.TempDir(), "input.raw")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewPCMReader(path, S16LE, 2, 44100, 44100, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &manualClock{now: time.Unix(0, 0), ticks: make(chan time.Time)}
+	reader.clock = clk
+	ring := NewRing(4096)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reader.Run(ctx, ring)
+		close(done)
+	}()
+
+	// Play 200ms, keeping the buffer full so nothing underruns
+	for i := 0; i < 20; i++ {
+		waitFor(func() bool { return reader.buffer.Fill() >= reader.target })
+		written := ring.Written()
+		clk.advance(networkInterval)
+		waitFor(func() bool { return ring.Written() > written })
+	}
+	cancel()
+	<-done
+
+	// Playback is paced by the clock, so only 200ms of the file has been played
+	if written := ring.Written(); written != 8820 {
+		t.Errorf("Played %d samples in 200ms, want 8820", written)
+	}
+	latest := make([]float64, 256)
+	ring.Latest(latest)
+	if math.Abs(latest[len(latest)-1]-0.5) > 0.01 {
+		t.Errorf("Latest sample = %v, want 0.5", latest[len(latest)-1])
+	}
+}
+
+func TestPCMReaderRejectsUnknownFormat(t *testing.T) {
+	if _, err := NewPCMReader("-", "u8", 1, 44100, 44100, time.Millisecond); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	for _, bad := range []struct{ channels, rate int }{{0, 44100}, {2, 0}, {-1, -1}} {
+		if _, err := NewPCMReader("-", S16LE, bad.channels, bad.rate, 44100, time.Millisecond); err == nil {
+			t.Errorf("Expected an error for %d channels at %d Hz", bad.channels, bad.rate)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"sync"
+)
+
+// Recorder receives every sample written to a Ring, in order.
+type Recorder interface {
+	WriteAudio(samples []float64)
+}
+
+// Ring is a fixed-size circular buffer of samples, written by an audio source
+// and read by the visualiser. It is safe for concurrent use.
+type Ring struct {
+	mu       sync.Mutex
+	samples  []float64
+	next     int
+	written  int64
+	recorder Recorder
+}
+
+// NewRing creates a ring holding the last size samples.
+func NewRing(size int) *Ring {
+	return &Ring{samples: make([]float64, size)}
+}
+
+// Write appends samples, overwriting the oldest ones.
+func (r *Ring) Write(samples []float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.recorder != nil {
+		r.recorder.WriteAudio(samples)
+	}
+
+	for _, sample := range samples {
+		r.samples[r.next] = sample
+		r.next = (r.next + 1) % len(r.samples)
+	}
+	r.written += int64(len(samples))
+}
+
+// Latest copies the most recent len(dst) samples into dst, oldest first, and
+// returns the number of samples written so far. Missing history reads as
+// silence.
+func (r *Ring) Latest(dst []float64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := r.next - len(dst)
+	for i := range dst {
+		index := ((start+i)%len(r.samples) + len(r.samples)) % len(r.samples)
+		dst[i] = r.samples[index]
+	}
+	return r.written
+}
+
+// Record passes every sample written from now on to recorder as well, or
+// stops recording when recorder is nil.
+func (r *Ring) Record(recorder Recorder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorder = recorder
+}
+
+// Written returns the total number of samples written so far.
+func (r *Ring) Written() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.written
+}
@@ -0,0 +1,24 @@
+package audio
+
+import (
+	"testing"
+)
+
+func TestRingLatest(t *testing.T) {
+	ring := NewRing(4)
+	ring.Write([]float64{1, 2, 3})
+	ring.Write([]float64{4, 5})
+
+	latest := make([]float64, 3)
+	if written := ring.Latest(latest); written != 5 {
+		t.Errorf("Expected Latest to report 5 samples written, got %d", written)
+	}
+	for i, want := range []float64{3, 4, 5} {
+		if latest[i] != want {
+			t.Errorf("Sample %d: expected %v, got %v", i, want, latest[i])
+		}
+	}
+	if ring.Written() != 5 {
+		t.Errorf("Expected 5 samples written, got %d", ring.Written())
+	}
+}
@@ -0,0 +1,225 @@
+// Package autopilot finds musically sensible moments to change scene: new
+// sections, energy building up, drops and breakdowns, and decides what to
+// change at them.
+package autopilot
+
+import (
+	"math"
+)
+
+// Event is a moment in the music worth reacting to.
+type Event int
+
+const (
+	None      Event = iota
+	Section         // The timbre or harmony settled into something new
+	BuildUp         // Energy has been climbing for a while
+	Drop            // Energy jumped up suddenly
+	Breakdown       // Energy fell well below its recent level
+)
+
+func (e Event) String() string {
+	switch e {
+	case Section:
+		return "section"
+	case BuildUp:
+		return "build-up"
+	case Drop:
+		return "drop"
+	case Breakdown:
+		return "breakdown"
+	}
+	return "none"
+}
+
+const (
+	hopRate          = 6    // Feature averages kept per second
+	noveltyWindow    = 4.0  // Seconds on either side of a possible section change
+	noveltyThreshold = 0.6  // Novelty that counts as a new section
+	noveltyFloor     = 0.05 // Spread below which a feature is treated as steady
+	shortEnergy      = 0.25 // Seconds the short-term energy follows over
+	longEnergy       = 8.0  // Seconds the long-term energy follows over
+	buildWindow      = 4.0  // Seconds of climbing that make a build-up
+	buildRise        = 1.5  // Factor the energy must climb by over a build-up
+	dropWindow       = 1.0  // Seconds a drop jumps up from
+	dropRise         = 2.0  // Factor the energy must jump by for a drop
+	breakdownLevel   = 0.4  // Share of the long-term energy that makes a breakdown
+	silence          = 1e-3 // Long-term energy below which nothing is detected
+)
+
+// Detector watches successive frames for events.
+type Detector struct {
+	ticksPerHop int
+	ticks       int
+	sum         []float64 // Features summed over the current hop
+	history     [][]float64
+	novelty     [3]float64 // Latest novelty values, newest first
+	quiet       int        // Hops before another section can be detected
+
+	short, long   float64
+	shortRate     float64
+	longRate      float64
+	energies      []float64 // Short-term energy at each hop, oldest first
+	building      bool      // A build-up has been reported and not yet resolved
+	broken        bool      // A breakdown has been reported and not yet recovered
+	dropQuiet     int       // Hops before another drop can be detected
+	energyStarted bool
+}
+
+// NewDetector creates a detector expecting Process to be called updateRate
+// times per second.
+func NewDetector(updateRate float64) *Detector {
+	return &Detector{
+		ticksPerHop: max(1, int(updateRate/hopRate)),
+		shortRate:   1 - math.Exp(-1/(shortEnergy*updateRate)),
+		longRate:    1 - math.Exp(-1/(longEnergy*updateRate)),
+	}
+}
+
+// Process takes the features and energy of one frame and returns the event
+// they complete, if any. Features should be roughly between 0 and 1, and
+// always the same length.
+func (d *Detector) Process(features []float64, energy float64) Event {
+	if !d.energyStarted {
+		d.short, d.long, d.energyStarted = energy, energy, true
+	}
+	d.short += (energy - d.short) * d.shortRate
+	d.long += (energy - d.long) * d.longRate
+
+	if d.sum == nil {
+		d.sum = make([]float64, len(features))
+	}
+	for i, f := range features {
+		d.sum[i] += f
+	}
+	d.ticks++
+	if d.ticks < d.ticksPerHop {
+		return None
+	}
+
+	// A hop is complete
+	mean := make([]float64, len(d.sum))
+	for i := range d.sum {
+		mean[i] = d.sum[i] / float64(d.ticks)
+		d.sum[i] = 0
+	}
+	d.ticks = 0
+
+	energyEvent := d.hopEnergy()
+	sectionEvent := d.hopNovelty(mean)
+	if energyEvent != None {
+		return energyEvent
+	}
+	return sectionEvent
+}
+
+// hopNovelty adds a hop of features to the history and reports a section
+// change when the novelty peaks above the threshold.
+func (d *Detector) hopNovelty(mean []float64) Event {
+	half := int(noveltyWindow * hopRate)
+	d.history = append(d.history, mean)
+	if len(d.history) > 2*half {
+		d.history = d.history[1:]
+	}
+	d.quiet = max(0, d.quiet-1)
+	if len(d.history) < 2*half {
+		return None
+	}
+
+	d.novelty[2], d.novelty[1] = d.novelty[1], d.novelty[0]
+	d.novelty[0] = Novelty(d.history[:half], d.history[half:])
+
+	// Report the peak once it has passed
+	peak := d.novelty[1]
+	if d.quiet == 0 && peak > noveltyThreshold && peak >= d.novelty[2] && peak > d.novelty[0] {
+		d.quiet = half
+		return Section
+	}
+	return None
+}
+
+// Novelty measures how different the features in after are from those in
+// before: the distance between their means in units of their spread, averaged
+// over the features.
+func Novelty(before, after [][]float64) float64 {
+	if len(before) == 0 || len(after) == 0 {
+		return 0
+	}
+	var total float64
+	dims := len(before[0])
+	for i := 0; i < dims; i++ {
+		var sumBefore, sumAfter, sumSq float64
+		for _, f := range before {
+			sumBefore += f[i]
+			sumSq += f[i] * f[i]
+		}
+		for _, f := range after {
+			sumAfter += f[i]
+			sumSq += f[i] * f[i]
+		}
+		n := float64(len(before) + len(after))
+		mean := (sumBefore + sumAfter) / n
+		spread := math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+		difference := sumAfter/float64(len(after)) - sumBefore/float64(len(before))
+		z := difference / math.Max(spread, noveltyFloor)
+		total += z * z
+	}
+	return math.Sqrt(total / float64(dims))
+}
+
+// hopEnergy tracks the short-term energy hop by hop for build-ups, drops
+// and breakdowns.
+func (d *Detector) hopEnergy() Event {
+	keep := int(buildWindow * hopRate)
+	d.energies = append(d.energies, d.short)
+	if len(d.energies) > keep+1 {
+		d.energies = d.energies[1:]
+	}
+	d.dropQuiet = max(0, d.dropQuiet-1)
+	if d.long < silence {
+		return None
+	}
+
+	// A drop is a sudden jump from a low point in the last moment
+	dropHops := int(dropWindow * hopRate)
+	low := d.short
+	for _, e := range d.energies[max(0, len(d.energies)-1-dropHops):] {
+		low = math.Min(low, e)
+	}
+	if d.dropQuiet == 0 && d.short > dropRise*low && d.short > d.long {
+		d.dropQuiet = keep
+		d.building, d.broken = false, false
+		return Drop
+	}
+
+	if d.short < breakdownLevel*d.long {
+		if !d.broken {
+			d.broken, d.building = true, false
+			return Breakdown
+		}
+		return None
+	}
+	if d.short > 0.7*d.long {
+		d.broken = false
+	}
+
+	// A build-up climbs steadily, still climbing in its second half, and the
+	// climb out of a drop isn't one
+	if !d.building && d.dropQuiet == 0 && len(d.energies) > keep &&
+		d.short > buildRise*d.energies[0] && d.short > math.Sqrt(buildRise)*d.energies[keep/2] {
+		rising := 0
+		for i := 1; i < len(d.energies); i++ {
+			if d.energies[i] >= d.energies[i-1] {
+				rising++
+			}
+		}
+		if float64(rising) >= 0.8*float64(keep) {
+			d.building = true
+			return BuildUp
+		}
+	}
+	if d.building && d.short < d.energies[0] {
+		d.building = false
+	}
+	return None
+}
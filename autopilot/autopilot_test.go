@@ -0,0 +1,123 @@
+package autopilot
+
+import (
+	"math/rand"
+	"testing"
+)
+
+const rate = 60
+
+// feed runs the detector over seconds of frames and returns the events with
+// the second each arrived.
+func feed(d *Detector, seconds float64, frame func(t float64) ([]float64, float64), start float64) map[Event][]float64 {
+	events := make(map[Event][]float64)
+	for i := 0; i < int(seconds*rate); i++ {
+		t := start + float64(i)/rate
+		features, energy := frame(t)
+		if e := d.Process(features, energy); e != None {
+			events[e] = append(events[e], t)
+		}
+	}
+	return events
+}
+
+func TestSection(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d := NewDetector(rate)
+	frame := func(t float64) ([]float64, float64) {
+		base := 0.3
+		if t >= 20 {
+			base = 0.7
+		}
+		features := make([]float64, 8)
+		for i := range features {
+			features[i] = base + (rng.Float64()-0.5)*0.1
+		}
+		return features, 0.5
+	}
+	events := feed(d, 40, frame, 0)
+	sections := events[Section]
+	if len(sections) != 1 {
+		t.Fatalf("Expected one section, got %v", sections)
+	}
+	if sections[0] < 20 || sections[0] > 20+noveltyWindow+1 {
+		t.Errorf("The section was found at %.1fs, want soon after 20s", sections[0])
+	}
+}
+
+func TestSteadyMusicHasNoSections(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	d := NewDetector(rate)
+	frame := func(t float64) ([]float64, float64) {
+		features := make([]float64, 8)
+		for i := range features {
+			features[i] = 0.5 + (rng.Float64()-0.5)*0.4
+		}
+		return features, 0.4 + rng.Float64()*0.2
+	}
+	events := feed(d, 60, frame, 0)
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %v", events)
+	}
+}
+
+func TestBuildUpAndDrop(t *testing.T) {
+	d := NewDetector(rate)
+	features := []float64{0.5}
+	frame := func(t float64) ([]float64, float64) {
+		switch {
+		case t < 10:
+			return features, 0.3
+		case t < 18:
+			// Climb, then a moment of silence before the drop
+			return features, 0.3 + (t-10)*0.1
+		case t < 18.5:
+			return features, 0.05
+		}
+		return features, 1.5
+	}
+	events := feed(d, 25, frame, 0)
+	if builds := events[BuildUp]; len(builds) != 1 || builds[0] < 10 || builds[0] > 18 {
+		t.Errorf("Expected a build-up between 10s and 18s, got %v", builds)
+	}
+	if drops := events[Drop]; len(drops) != 1 || drops[0] < 18.5 || drops[0] > 19.5 {
+		t.Errorf("Expected a drop just after 18.5s, got %v", drops)
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	d := NewDetector(rate)
+	features := []float64{0.5}
+	frame := func(t float64) ([]float64, float64) {
+		if t < 20 {
+			return features, 1
+		}
+		return features, 0.2
+	}
+	events := feed(d, 30, frame, 0)
+	if breaks := events[Breakdown]; len(breaks) != 1 || breaks[0] < 20 || breaks[0] > 22 {
+		t.Errorf("Expected one breakdown just after 20s, got %v", breaks)
+	}
+	if len(events[Drop]) != 0 || len(events[BuildUp]) != 0 {
+		t.Errorf("Expected only a breakdown, got %v", events)
+	}
+}
+
+func TestSilence(t *testing.T) {
+	d := NewDetector(rate)
+	events := feed(d, 20, func(float64) ([]float64, float64) { return []float64{0}, 0 }, 0)
+	if len(events) != 0 {
+		t.Errorf("Expected no events in silence, got %v", events)
+	}
+}
+
+func TestNovelty(t *testing.T) {
+	same := [][]float64{{0.5, 0.5}, {0.5, 0.5}}
+	if n := Novelty(same, same); n != 0 {
+		t.Errorf("Novelty between identical windows is %v, want 0", n)
+	}
+	changed := [][]float64{{0.9, 0.5}, {0.9, 0.5}}
+	if n := Novelty(same, changed); n < 1 {
+		t.Errorf("Novelty after a change is %v, want above 1", n)
+	}
+}
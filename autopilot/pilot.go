@@ -0,0 +1,134 @@
+package autopilot
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultDwell   = 30.0 // Seconds a scene is kept at least when not set
+	paletteShare   = 0.25 // Share of the dwell between palette changes
+	restlessDwells = 4    // Dwells without a musical reason before changing anyway
+)
+
+// Change is what the pilot wants done to the scene.
+type Change int
+
+const (
+	Stay        Change = iota
+	NewScene           // Blend into another scene
+	DropScene          // Cut to another scene without waiting for a beat
+	NewPalette         // Blend to another colour scheme
+	DarkPalette        // Blend to a darker colour scheme
+)
+
+// Transition returns the kind of transition to make the change with, given
+// the one the new scene asks for. A drop always cuts.
+func (c Change) Transition(kind string) string {
+	if c == DropScene {
+		return "cut"
+	}
+	return kind
+}
+
+// Pilot changes the scene by itself at musically sensible moments: a new
+// scene on a new section or a drop, a new palette on a build-up or
+// breakdown.
+type Pilot struct {
+	On        bool
+	Dwell     float64 // Seconds a scene is kept at least
+	Presets   []int   // Presets the pilot may pick, all of them when empty
+	Event     Event   // Latest event heard
+	EventAt   int64   // Tick of the latest event
+	detector  *Detector
+	rate      float64
+	changedAt int64 // Tick the scene last changed
+	paletteAt int64 // Tick the palette last changed
+}
+
+// NewPilot creates a pilot, switched off, for Update to be called
+// updateRate times per second.
+func NewPilot(updateRate float64) *Pilot {
+	return &Pilot{Dwell: defaultDwell, detector: NewDetector(updateRate), rate: updateRate}
+}
+
+// Whitelist limits the presets the pilot picks from to those named in the
+// comma separated list names, out of all the presets.
+func (p *Pilot) Whitelist(names string, presets []string) error {
+	p.Presets = p.Presets[:0]
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		index := slices.Index(presets, name)
+		if index < 0 {
+			return fmt.Errorf("unknown autopilot preset %q", name)
+		}
+		p.Presets = append(p.Presets, index)
+	}
+	return nil
+}
+
+// Update listens for events in the features and energy of the frame at
+// tick and, when switched on and not busy with another change, returns the
+// change to make once the scene has been shown long enough. The pilot
+// always listens, so it is ready as soon as it is switched on.
+func (p *Pilot) Update(tick int64, features []float64, energy float64, busy bool) Change {
+	event := p.detector.Process(features, energy)
+	if event != None {
+		p.Event, p.EventAt = event, tick
+	}
+
+	if !p.On || busy {
+		return Stay
+	}
+	shown := float64(tick-p.changedAt) / p.rate
+	switch {
+	case event == Section || event == Drop:
+		if shown >= p.Dwell {
+			if event == Drop {
+				return DropScene
+			}
+			return NewScene
+		}
+	case event == BuildUp || event == Breakdown:
+		if float64(tick-p.paletteAt)/p.rate >= p.Dwell*paletteShare {
+			if event == Breakdown {
+				return DarkPalette
+			}
+			return NewPalette
+		}
+	case shown >= restlessDwells*p.Dwell:
+		return NewScene
+	}
+	return Stay
+}
+
+// SceneChanged notes that the scene changed at tick, by the pilot or not,
+// so it is kept for a dwell.
+func (p *Pilot) SceneChanged(tick int64) {
+	p.changedAt = tick
+}
+
+// PaletteChanged notes that the palette changed at tick.
+func (p *Pilot) PaletteChanged(tick int64) {
+	p.paletteAt = tick
+}
+
+// Pick returns a preset other than current, out of count presets, for a
+// new scene, or false when there is none it may pick.
+func (p *Pilot) Pick(current, count int, rng *rand.Rand) (int, bool) {
+	var others []int
+	for i := 0; i < count; i++ {
+		if i != current && (len(p.Presets) == 0 || slices.Contains(p.Presets, i)) {
+			others = append(others, i)
+		}
+	}
+	if len(others) == 0 {
+		return -1, false
+	}
+	return others[rng.Intn(len(others))], true
+}
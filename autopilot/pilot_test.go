@@ -0,0 +1,138 @@
+package autopilot
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWhitelist(t *testing.T) {
+	p := NewPilot(rate)
+	presets := []string{"Calm", "Lift", "Drop"}
+	if err := p.Whitelist("Calm,Missing", presets); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+	if err := p.Whitelist(" Calm, Drop,", presets); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the whitelisted presets are picked, never the current one
+	rng := rand.New(rand.NewSource(1))
+	current := 0
+	for i := 0; i < 10; i++ {
+		next, ok := p.Pick(current, len(presets), rng)
+		if !ok || next == current || next == 1 {
+			t.Fatalf("Moved from preset %d to %d", current, next)
+		}
+		current = next
+	}
+
+	if _, ok := p.Pick(-1, 0, rng); ok {
+		t.Error("Picked a preset with none loaded")
+	}
+	p.Whitelist("Drop", presets)
+	if _, ok := p.Pick(2, len(presets), rng); ok {
+		t.Error("Picked a preset other than the only one allowed, which is showing")
+	}
+}
+
+func TestChangeTransition(t *testing.T) {
+	tests := []struct {
+		change Change
+		kind   string
+		want   string
+	}{
+		{NewScene, "wipe", "wipe"},
+		{NewScene, "dissolve", "dissolve"},
+		{DropScene, "wipe", "cut"},
+		{DropScene, "dissolve", "cut"},
+		{NewPalette, "fade", "fade"},
+	}
+	for _, test := range tests {
+		if got := test.change.Transition(test.kind); got != test.want {
+			t.Errorf("Change %d with %q transitions by %q, want %q", test.change, test.kind, got, test.want)
+		}
+	}
+}
+
+func TestPilot(t *testing.T) {
+	p := NewPilot(rate)
+	p.On, p.Dwell = true, 10
+	features := []float64{0.5}
+	var tick int64
+
+	// run feeds the pilot seconds of energy, from 0 at the start of the run,
+	// and makes the changes it asks for like the visualiser does
+	run := func(seconds float64, energy func(t float64) float64, busy bool) map[Change][]float64 {
+		changes := make(map[Change][]float64)
+		for i := 0; i < int(seconds*rate); i++ {
+			tick++
+			c := p.Update(tick, features, energy(float64(i)/rate), busy)
+			switch c {
+			case NewScene, DropScene:
+				p.SceneChanged(tick)
+			case NewPalette, DarkPalette:
+				p.PaletteChanged(tick)
+			default:
+				continue
+			}
+			changes[c] = append(changes[c], float64(i)/rate)
+		}
+		return changes
+	}
+	steady := func(float64) float64 { return 0.5 }
+	drop := func(t float64) float64 {
+		switch {
+		case t < 10:
+			return 0.5
+		case t < 10.5:
+			return 0.05
+		}
+		return 1.5
+	}
+
+	// Nothing changes within the dwell
+	if changes := run(9, steady, false); len(changes) != 0 {
+		t.Errorf("The scene changed within the dwell: %v", changes)
+	}
+	if p.Event != None {
+		t.Errorf("Steady music gave a %s", p.Event)
+	}
+
+	// Without musical events the pilot moves on eventually
+	changes := run(restlessDwells*10, steady, false)
+	if scenes := changes[NewScene]; len(scenes) != 1 || scenes[0] < restlessDwells*10-9.1 || scenes[0] > restlessDwells*10-8.9 {
+		t.Errorf("Restless scene changes at %v, want one four dwells after starting", scenes)
+	}
+
+	// Busy with another change the pilot leaves a drop alone
+	if changes := run(12, drop, true); len(changes) != 0 || p.Event != Drop {
+		t.Errorf("Busy with another change the pilot made %v after a %s", changes, p.Event)
+	}
+
+	// Otherwise a drop after the dwell cuts to a new scene
+	p.SceneChanged(tick)
+	p.detector = NewDetector(rate)
+	changes = run(12, drop, false)
+	if drops := changes[DropScene]; len(drops) != 1 || drops[0] < 10.5 || drops[0] > 11.5 {
+		t.Errorf("Drop scene changes at %v, want one just after 10.5s", drops)
+	}
+
+	// A breakdown darkens the palette, once a share of the dwell has passed since it last changed
+	breakdown := func(t float64) float64 {
+		if t < 1 {
+			return 1
+		}
+		return 0.2
+	}
+	p.detector = NewDetector(rate)
+	p.PaletteChanged(tick)
+	if changes := run(5, breakdown, false); len(changes) != 0 || p.Event != Breakdown {
+		t.Errorf("Soon after the palette changed the pilot made %v after a %s", changes, p.Event)
+	}
+	p.detector = NewDetector(rate)
+	p.PaletteChanged(tick - 10*rate)
+	changes = run(5, breakdown, false)
+	if darks := changes[DarkPalette]; len(darks) != 1 || darks[0] < 1 || darks[0] > 3 {
+		t.Errorf("Dark palette changes at %v, want one just after 1s", darks)
+	}
+}
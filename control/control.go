@@ -0,0 +1,225 @@
+// Package control serves a performer's control surface over HTTP, so the
+// HUD, presets and a preview of the output can be kept off the projector.
+package control
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const maxPending = 64 // Actions queued before new ones are dropped
+
+//go:embed index.html
+var indexPage []byte
+
+// Action is a change requested from the control surface.
+type Action struct {
+	Name  string  `json:"name"`
+	Text  string  `json:"text,omitempty"`
+	Value float64 `json:"value,omitempty"`
+}
+
+// Server serves the control surface. State and thumbnails are published by
+// the visualiser; actions are queued until it collects them with Pending.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+	actions  chan Action
+	token    string // Token every request but the page itself must carry, if set
+
+	mu        sync.Mutex
+	state     []byte      // Latest state as JSON
+	thumbnail *image.RGBA // Latest preview of the output
+}
+
+// Listen opens the control surface on addr, e.g. "localhost:8080". When
+// token is set, requests must carry it as the token query parameter. Any
+// machine that can reach a surface could drive the show, so one reachable
+// from other machines needs a token.
+func Listen(addr, token string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" && !isLoopback(listener.Addr()) {
+		listener.Close()
+		return nil, fmt.Errorf("control surface on %s is reachable from other machines and needs a token", addr)
+	}
+
+	s := &Server{
+		listener: listener,
+		actions:  make(chan Action, maxPending),
+		token:    token,
+		state:    []byte("{}"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.serveIndex)
+	mux.HandleFunc("GET /state", s.authorized(s.serveState))
+	mux.HandleFunc("GET /thumbnail.png", s.authorized(s.serveThumbnail))
+	mux.HandleFunc("POST /action", s.authorized(s.serveAction))
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	return s, nil
+}
+
+// Addr returns the address the surface is served on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// URL returns the address to open the surface at, with its token.
+func (s *Server) URL() string {
+	u := url.URL{Scheme: "http", Host: s.listener.Addr().String(), Path: "/"}
+	if s.token != "" {
+		u.RawQuery = url.Values{"token": {s.token}}.Encode()
+	}
+	return u.String()
+}
+
+// Serve handles requests until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.server.Close()
+	}()
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Control surface stopped: %v", err)
+	}
+}
+
+// SetState publishes state, encoded as JSON, to the surface.
+func (s *Server) SetState(state any) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.state = data
+	s.mu.Unlock()
+	return nil
+}
+
+// SetThumbnail publishes a copy of img as the preview of the output.
+func (s *Server) SetThumbnail(img image.Image) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.thumbnail == nil || s.thumbnail.Bounds() != img.Bounds() {
+		s.thumbnail = image.NewRGBA(img.Bounds())
+	}
+	draw.Draw(s.thumbnail, img.Bounds(), img, img.Bounds().Min, draw.Src)
+}
+
+// Pending appends the actions requested since the last call to dst without
+// blocking.
+func (s *Server) Pending(dst []Action) []Action {
+	for {
+		select {
+		case action := <-s.actions:
+			dst = append(dst, action)
+		default:
+			return dst
+		}
+	}
+}
+
+// authorized wraps handler so it only serves requests carrying the token.
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+func (s *Server) serveState(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(state)
+}
+
+func (s *Server) serveThumbnail(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.thumbnail == nil {
+		http.Error(w, "no frame yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	png.Encode(w, s.thumbnail)
+}
+
+// serveAction queues an action. Only JSON from the surface's own page is
+// accepted, so another site open in the performer's browser can't post
+// actions: cross-origin JSON needs a preflight this server never allows, and
+// a plain form can't send it.
+func (s *Server) serveAction(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		http.Error(w, "actions must be sent as application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin actions are not allowed", http.StatusForbidden)
+		return
+	}
+
+	var action Action
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&action); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if action.Name == "" {
+		http.Error(w, "missing action name", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.actions <- action:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "too many pending actions", http.StatusServiceUnavailable)
+	}
+}
+
+// sameOrigin reports whether a request came from a page served by this
+// server. Requests without an Origin, such as from curl, are not from a
+// browser page and are allowed; they need the token like any other when
+// the surface is reachable from other machines.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// isLoopback reports whether addr can only be reached from this machine.
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
@@ -0,0 +1,181 @@
+package control
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func startServer(t *testing.T, token string) (*Server, string) {
+	t.Helper()
+	s, err := Listen("127.0.0.1:0", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Serve(ctx)
+	return s, "http://" + s.Addr().String()
+}
+
+func TestActionsAreQueued(t *testing.T) {
+	s, url := startServer(t, "")
+
+	for _, body := range []string{`{"name":"preset","value":2}`, `{"name":"waveform","text":"smooth"}`} {
+		resp, err := http.Post(url+"/action", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("POST %s: status %d", body, resp.StatusCode)
+		}
+	}
+
+	actions := s.Pending(nil)
+	want := []Action{{Name: "preset", Value: 2}, {Name: "waveform", Text: "smooth"}}
+	if len(actions) != len(want) {
+		t.Fatalf("Pending() = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("Action %d = %v, want %v", i, actions[i], want[i])
+		}
+	}
+	if actions := s.Pending(nil); len(actions) != 0 {
+		t.Errorf("Pending() after draining = %v", actions)
+	}
+}
+
+func TestRejectsInvalidAction(t *testing.T) {
+	_, url := startServer(t, "")
+	for _, body := range []string{`{"value":1}`, `not json`} {
+		resp, err := http.Post(url+"/action", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST %s: status %d, want %d", body, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestRejectsOtherSites(t *testing.T) {
+	_, url := startServer(t, "")
+	tests := []struct {
+		contentType string
+		origin      string
+		want        int
+	}{
+		{"application/json", "", http.StatusNoContent},
+		{"application/json; charset=utf-8", url, http.StatusNoContent},
+		{"text/plain", "", http.StatusUnsupportedMediaType},
+		{"application/x-www-form-urlencoded", url, http.StatusUnsupportedMediaType},
+		{"application/json", "http://example.com", http.StatusForbidden},
+		{"application/json", "null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodPost, url+"/action", strings.NewReader(`{"name":"fluid","value":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", tt.contentType)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST as %s from %q: status %d, want %d", tt.contentType, tt.origin, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	if s, err := Listen(":0", ""); err == nil {
+		s.listener.Close()
+		t.Error("Expected an error serving other machines without a token")
+	}
+
+	_, url := startServer(t, "secret")
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodGet, "/state", http.StatusUnauthorized},
+		{http.MethodGet, "/state?token=wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/state?token=secret", http.StatusOK},
+		{http.MethodGet, "/thumbnail.png", http.StatusUnauthorized},
+		{http.MethodPost, "/action", http.StatusUnauthorized},
+		{http.MethodPost, "/action?token=secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, url+tt.path, strings.NewReader(`{"name":"fluid","value":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestStateAndThumbnail(t *testing.T) {
+	s, url := startServer(t, "")
+
+	resp, err := http.Get(url + "/thumbnail.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Thumbnail before the first frame: status %d", resp.StatusCode)
+	}
+
+	if err := s.SetState(map[string]any{"preset": 1}); err != nil {
+		t.Fatal(err)
+	}
+	frame := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	frame.Set(1, 1, color.RGBA{R: 255, A: 255})
+	s.SetThumbnail(frame)
+
+	resp, err = http.Get(url + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != `{"preset":1}` {
+		t.Errorf("State = %s", body)
+	}
+
+	resp, err = http.Get(url + "/thumbnail.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	img, err := png.Decode(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := img.At(1, 1).RGBA(); img.Bounds().Dx() != 4 || r != 0xffff {
+		t.Errorf("Thumbnail is %v with %v at (1, 1)", img.Bounds(), img.At(1, 1))
+	}
+}
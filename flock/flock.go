@@ -0,0 +1,195 @@
+// Package flock moves a flock of boids, after Craig Reynolds' "Flocks,
+// Herds, and Schools", using a spatial hash so each boid only looks at its
+// neighbours.
+package flock
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Boid is one member of the flock, with its position in pixels and its
+// velocity in pixels per tick.
+type Boid struct {
+	X, Y   float64
+	VX, VY float64
+}
+
+// Params weighs the rules the boids follow.
+type Params struct {
+	Radius           float64 // Distance within which boids see each other
+	SeparationRadius float64 // Distance within which boids move apart
+	Separation       float64 // Steering away from crowding neighbours
+	Alignment        float64 // Steering towards the neighbours' heading
+	Cohesion         float64 // Steering towards the neighbours' centre
+	Formation        float64 // Steering onto a ring around the centre of the screen
+	FormationRadius  float64
+	MinSpeed         float64
+	MaxSpeed         float64
+}
+
+// DefaultParams is a loose, wandering flock.
+var DefaultParams = Params{
+	Radius:           40,
+	SeparationRadius: 12,
+	Separation:       0.05,
+	Alignment:        0.05,
+	Cohesion:         0.005,
+	MinSpeed:         1,
+	MaxSpeed:         4,
+}
+
+// Flock is a set of boids on a screen that wraps at its edges.
+type Flock struct {
+	Boids  []Boid
+	Params Params
+	Width  float64
+	Height float64
+
+	// Spatial hash: the first boid in each cell, and the next boid in the same cell
+	columns, rows int
+	cellSize      float64
+	heads         []int32
+	next          []int32
+}
+
+// New scatters count boids with random headings over a width by height
+// screen.
+func New(count int, width, height float64, rng *rand.Rand) *Flock {
+	f := &Flock{Boids: make([]Boid, count), Params: DefaultParams, Width: width, Height: height}
+	for i := range f.Boids {
+		angle := rng.Float64() * 2 * math.Pi
+		speed := f.Params.MinSpeed + rng.Float64()*(f.Params.MaxSpeed-f.Params.MinSpeed)
+		f.Boids[i] = Boid{X: rng.Float64() * width, Y: rng.Float64() * height, VX: math.Cos(angle) * speed, VY: math.Sin(angle) * speed}
+	}
+	return f
+}
+
+// Resize changes the screen the boids fly over, scaling their positions.
+func (f *Flock) Resize(width, height float64) {
+	if width == f.Width && height == f.Height {
+		return
+	}
+	for i := range f.Boids {
+		f.Boids[i].X *= width / f.Width
+		f.Boids[i].Y *= height / f.Height
+	}
+	f.Width, f.Height = width, height
+}
+
+// Scatter pushes every boid within radius of (x, y) away from it, hardest
+// at the centre.
+func (f *Flock) Scatter(x, y, strength, radius float64) {
+	for i := range f.Boids {
+		b := &f.Boids[i]
+		dx, dy := b.X-x, b.Y-y
+		distance := math.Hypot(dx, dy)
+		if distance == 0 || distance > radius {
+			continue
+		}
+		push := strength * (1 - distance/radius)
+		b.VX += dx / distance * push
+		b.VY += dy / distance * push
+	}
+}
+
+// Step moves the flock on by one tick.
+func (f *Flock) Step() {
+	f.hash()
+
+	p := f.Params
+	centreX, centreY := f.Width/2, f.Height/2
+	radiusSq := p.Radius * p.Radius
+	separationSq := p.SeparationRadius * p.SeparationRadius
+
+	for i := range f.Boids {
+		b := &f.Boids[i]
+		var count int
+		var sumX, sumY, sumVX, sumVY, awayX, awayY float64
+		column, row := f.cellOf(b.X, b.Y)
+		for r := max(0, row-1); r <= min(f.rows-1, row+1); r++ {
+			for c := max(0, column-1); c <= min(f.columns-1, column+1); c++ {
+				for j := f.heads[r*f.columns+c]; j >= 0; j = f.next[j] {
+					if int(j) == i {
+						continue
+					}
+					o := &f.Boids[j]
+					dx, dy := o.X-b.X, o.Y-b.Y
+					distanceSq := dx*dx + dy*dy
+					if distanceSq > radiusSq {
+						continue
+					}
+					count++
+					sumX += dx
+					sumY += dy
+					sumVX += o.VX
+					sumVY += o.VY
+					if distanceSq < separationSq && distanceSq > 0 {
+						awayX -= dx / distanceSq
+						awayY -= dy / distanceSq
+					}
+				}
+			}
+		}
+
+		if count > 0 {
+			n := float64(count)
+			b.VX += awayX*p.Separation*p.SeparationRadius + (sumVX/n-b.VX)*p.Alignment + sumX/n*p.Cohesion
+			b.VY += awayY*p.Separation*p.SeparationRadius + (sumVY/n-b.VY)*p.Alignment + sumY/n*p.Cohesion
+		}
+
+		if p.Formation > 0 {
+			// Steer towards the nearest point on the ring
+			dx, dy := b.X-centreX, b.Y-centreY
+			if distance := math.Hypot(dx, dy); distance > 0 {
+				off := p.FormationRadius - distance
+				b.VX += dx / distance * off * p.Formation * 0.01
+				b.VY += dy / distance * off * p.Formation * 0.01
+			}
+		}
+
+		speed := math.Hypot(b.VX, b.VY)
+		if speed > p.MaxSpeed {
+			b.VX, b.VY = b.VX/speed*p.MaxSpeed, b.VY/speed*p.MaxSpeed
+		} else if speed < p.MinSpeed && speed > 0 {
+			b.VX, b.VY = b.VX/speed*p.MinSpeed, b.VY/speed*p.MinSpeed
+		}
+	}
+
+	for i := range f.Boids {
+		b := &f.Boids[i]
+		b.X = math.Mod(b.X+b.VX+f.Width, f.Width)
+		b.Y = math.Mod(b.Y+b.VY+f.Height, f.Height)
+	}
+}
+
+// hash sorts the boids into cells the size of their sight radius, so a
+// boid's neighbours are all in its own and the eight surrounding cells.
+func (f *Flock) hash() {
+	f.cellSize = math.Max(f.Params.Radius, 1)
+	f.columns = max(1, int(math.Ceil(f.Width/f.cellSize)))
+	f.rows = max(1, int(math.Ceil(f.Height/f.cellSize)))
+	cells := f.columns * f.rows
+	if cap(f.heads) < cells {
+		f.heads = make([]int32, cells)
+	}
+	f.heads = f.heads[:cells]
+	for i := range f.heads {
+		f.heads[i] = -1
+	}
+	if cap(f.next) < len(f.Boids) {
+		f.next = make([]int32, len(f.Boids))
+	}
+	f.next = f.next[:len(f.Boids)]
+
+	for i, b := range f.Boids {
+		column, row := f.cellOf(b.X, b.Y)
+		cell := row*f.columns + column
+		f.next[i] = f.heads[cell]
+		f.heads[cell] = int32(i)
+	}
+}
+
+func (f *Flock) cellOf(x, y float64) (column, row int) {
+	return min(f.columns-1, max(0, int(x/f.cellSize))), min(f.rows-1, max(0, int(y/f.cellSize)))
+}
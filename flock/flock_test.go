@@ -0,0 +1,118 @@
+package flock
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSeparation(t *testing.T) {
+	f := &Flock{Params: DefaultParams, Width: 200, Height: 200}
+	f.Params.Alignment, f.Params.Cohesion = 0, 0
+	f.Boids = []Boid{{X: 100, Y: 100, VX: 0, VY: 1}, {X: 105, Y: 100, VX: 0, VY: 1}}
+
+	before := f.Boids[1].X - f.Boids[0].X
+	for step := 0; step < 10; step++ {
+		f.Step()
+	}
+	if after := f.Boids[1].X - f.Boids[0].X; after <= before {
+		t.Errorf("Boids %.2f apart moved to %.2f, want further apart", before, after)
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	f := New(200, 400, 400, rng)
+	f.Params.Radius = 600 // Everyone sees everyone
+	f.Params.Separation, f.Params.Cohesion = 0, 0
+	for i := range f.Boids {
+		// Headings spread across the right half
+		angle := (rng.Float64() - 0.5) * math.Pi
+		f.Boids[i].VX, f.Boids[i].VY = 2*math.Cos(angle), 2*math.Sin(angle)
+	}
+
+	order := func() float64 {
+		var vx, vy, speed float64
+		for _, b := range f.Boids {
+			vx += b.VX
+			vy += b.VY
+			speed += math.Hypot(b.VX, b.VY)
+		}
+		return math.Hypot(vx, vy) / speed
+	}
+
+	before := order()
+	for step := 0; step < 100; step++ {
+		f.Step()
+	}
+	if after := order(); after < 0.95 {
+		t.Errorf("Headings went from %.2f to %.2f aligned, want above 0.95", before, after)
+	}
+}
+
+func TestScatter(t *testing.T) {
+	f := &Flock{Params: DefaultParams, Width: 200, Height: 200}
+	f.Boids = []Boid{{X: 110, Y: 100}, {X: 100, Y: 80}, {X: 190, Y: 100}}
+	f.Scatter(100, 100, 2, 50)
+
+	if b := f.Boids[0]; b.VX <= 0 || b.VY != 0 {
+		t.Errorf("Boid right of the blast moves (%.2f, %.2f), want +x", b.VX, b.VY)
+	}
+	if b := f.Boids[1]; b.VY >= 0 {
+		t.Errorf("Boid above the blast moves %.2f in y, want up", b.VY)
+	}
+	if b := f.Boids[2]; b.VX != 0 || b.VY != 0 {
+		t.Errorf("Boid beyond the radius moves (%.2f, %.2f), want still", b.VX, b.VY)
+	}
+}
+
+func TestFormation(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	f := New(300, 400, 400, rng)
+	f.Params.Formation = 1
+	f.Params.FormationRadius = 100
+
+	for step := 0; step < 300; step++ {
+		f.Step()
+	}
+	var off float64
+	for _, b := range f.Boids {
+		off += math.Abs(math.Hypot(b.X-200, b.Y-200) - 100)
+	}
+	if mean := off / float64(len(f.Boids)); mean > 25 {
+		t.Errorf("Boids are %.1f px from the ring on average, want within 25", mean)
+	}
+}
+
+func TestHashFindsEveryNeighbour(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	f := New(500, 300, 200, rng)
+	f.hash()
+
+	radius := f.Params.Radius
+	for i, b := range f.Boids {
+		found := map[int]bool{}
+		column, row := f.cellOf(b.X, b.Y)
+		for r := max(0, row-1); r <= min(f.rows-1, row+1); r++ {
+			for c := max(0, column-1); c <= min(f.columns-1, column+1); c++ {
+				for j := f.heads[r*f.columns+c]; j >= 0; j = f.next[j] {
+					found[int(j)] = true
+				}
+			}
+		}
+		for j, o := range f.Boids {
+			if math.Hypot(o.X-b.X, o.Y-b.Y) <= radius && !found[j] {
+				t.Fatalf("Boid %d misses neighbour %d", i, j)
+			}
+		}
+	}
+}
+
+func BenchmarkStep(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	f := New(5000, 1920, 1080, rng)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Step()
+	}
+}
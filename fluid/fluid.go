@@ -0,0 +1,209 @@
+// Package fluid simulates an incompressible fluid carrying dye on a grid,
+// after Jos Stam's "Real-Time Fluid Dynamics for Games".
+package fluid
+
+import "math"
+
+const iterations = 20 // Gauss-Seidel relaxation steps per solve
+
+// Field is a Width by Height grid of cells. Positions are in cells, with
+// (0, 0) the top-left corner of the grid, and velocities in cells per
+// second. The grid is closed by walls the fluid slides along.
+type Field struct {
+	Width, Height int
+	Viscosity     float64 // Velocity diffusion in cells² per second
+	Diffusion     float64 // Dye diffusion in cells² per second
+	Dissipation   float64 // Fraction of dye lost per second
+
+	u, v, u0, v0 []float64 // Velocity and scratch, with a border cell on each side
+	dye, dye0    []float64
+}
+
+// New creates a still, clear field width by height cells.
+func New(width, height int) *Field {
+	size := (width + 2) * (height + 2)
+	return &Field{
+		Width:  width,
+		Height: height,
+		u:      make([]float64, size),
+		v:      make([]float64, size),
+		u0:     make([]float64, size),
+		v0:     make([]float64, size),
+		dye:    make([]float64, size),
+		dye0:   make([]float64, size),
+	}
+}
+
+func (f *Field) index(i, j int) int {
+	return i + (f.Width+2)*j
+}
+
+// AddVelocity pushes the fluid within radius of (x, y) by (vx, vy), with
+// the push fading towards the edge of the circle.
+func (f *Field) AddVelocity(x, y, vx, vy, radius float64) {
+	f.splat(x, y, radius, func(k int, weight float64) {
+		f.u[k] += vx * weight
+		f.v[k] += vy * weight
+	})
+}
+
+// AddDye adds amount of dye within radius of (x, y).
+func (f *Field) AddDye(x, y, amount, radius float64) {
+	f.splat(x, y, radius, func(k int, weight float64) {
+		f.dye[k] += amount * weight
+	})
+}
+
+func (f *Field) splat(x, y, radius float64, apply func(k int, weight float64)) {
+	radius = math.Max(radius, 0.5)
+	for j := max(1, int(y-radius)); j <= min(f.Height, int(y+radius)+1); j++ {
+		for i := max(1, int(x-radius)); i <= min(f.Width, int(x+radius)+1); i++ {
+			// Cell i covers x from i-1 to i, so its centre is at i-0.5
+			distance := math.Hypot(float64(i)-0.5-x, float64(j)-0.5-y)
+			if distance < radius {
+				apply(f.index(i, j), 1-distance/radius)
+			}
+		}
+	}
+}
+
+// Velocity returns the fluid velocity at (x, y).
+func (f *Field) Velocity(x, y float64) (float64, float64) {
+	return f.sample(f.u, x+0.5, y+0.5), f.sample(f.v, x+0.5, y+0.5)
+}
+
+// Dye returns the amount of dye in cell (i, j), counting from 0.
+func (f *Field) Dye(i, j int) float64 {
+	return f.dye[f.index(i+1, j+1)]
+}
+
+// Step advances the simulation by dt seconds.
+func (f *Field) Step(dt float64) {
+	// Velocity: diffuse, make incompressible, carry along itself, and again
+	f.u0, f.u = f.u, f.u0
+	f.v0, f.v = f.v, f.v0
+	f.diffuse(1, f.u, f.u0, f.Viscosity, dt)
+	f.diffuse(2, f.v, f.v0, f.Viscosity, dt)
+	f.project()
+
+	f.u0, f.u = f.u, f.u0
+	f.v0, f.v = f.v, f.v0
+	f.advect(1, f.u, f.u0, f.u0, f.v0, dt)
+	f.advect(2, f.v, f.v0, f.u0, f.v0, dt)
+	f.project()
+
+	// Dye: diffuse and carry along the velocity
+	f.dye0, f.dye = f.dye, f.dye0
+	f.diffuse(0, f.dye, f.dye0, f.Diffusion, dt)
+	f.dye0, f.dye = f.dye, f.dye0
+	f.advect(0, f.dye, f.dye0, f.u, f.v, dt)
+
+	if f.Dissipation > 0 {
+		keep := math.Max(0, 1-f.Dissipation*dt)
+		for k := range f.dye {
+			f.dye[k] *= keep
+		}
+	}
+}
+
+func (f *Field) diffuse(b int, x, x0 []float64, rate, dt float64) {
+	a := dt * rate
+	if a == 0 {
+		copy(x, x0)
+		return
+	}
+	f.solve(b, x, x0, a, 1+4*a)
+}
+
+// solve relaxes x towards the solution of the implicit diffusion and
+// pressure equations.
+func (f *Field) solve(b int, x, x0 []float64, a, c float64) {
+	stride := f.Width + 2
+	for n := 0; n < iterations; n++ {
+		for j := 1; j <= f.Height; j++ {
+			for i := 1; i <= f.Width; i++ {
+				k := f.index(i, j)
+				x[k] = (x0[k] + a*(x[k-1]+x[k+1]+x[k-stride]+x[k+stride])) / c
+			}
+		}
+		f.setBoundary(b, x)
+	}
+}
+
+// advect moves d0 along the velocity (u, v) into d by tracing each cell
+// back in time.
+func (f *Field) advect(b int, d, d0, u, v []float64, dt float64) {
+	for j := 1; j <= f.Height; j++ {
+		for i := 1; i <= f.Width; i++ {
+			k := f.index(i, j)
+			d[k] = f.sample(d0, float64(i)-dt*u[k], float64(j)-dt*v[k])
+		}
+	}
+	f.setBoundary(b, d)
+}
+
+// sample interpolates field at (x, y) in grid coordinates, where cell i has
+// its centre at x = i.
+func (f *Field) sample(field []float64, x, y float64) float64 {
+	x = math.Max(0.5, math.Min(float64(f.Width)+0.5, x))
+	y = math.Max(0.5, math.Min(float64(f.Height)+0.5, y))
+	i0, j0 := int(x), int(y)
+	s1, t1 := x-float64(i0), y-float64(j0)
+	s0, t0 := 1-s1, 1-t1
+	return s0*(t0*field[f.index(i0, j0)]+t1*field[f.index(i0, j0+1)]) +
+		s1*(t0*field[f.index(i0+1, j0)]+t1*field[f.index(i0+1, j0+1)])
+}
+
+// project removes the divergent part of the velocity, leaving a flow that
+// neither gathers nor loses fluid anywhere.
+func (f *Field) project() {
+	stride := f.Width + 2
+	pressure, divergence := f.u0, f.v0
+	for j := 1; j <= f.Height; j++ {
+		for i := 1; i <= f.Width; i++ {
+			k := f.index(i, j)
+			divergence[k] = -0.5 * (f.u[k+1] - f.u[k-1] + f.v[k+stride] - f.v[k-stride])
+			pressure[k] = 0
+		}
+	}
+	f.setBoundary(0, divergence)
+	f.setBoundary(0, pressure)
+	f.solve(0, pressure, divergence, 1, 4)
+
+	for j := 1; j <= f.Height; j++ {
+		for i := 1; i <= f.Width; i++ {
+			k := f.index(i, j)
+			f.u[k] -= 0.5 * (pressure[k+1] - pressure[k-1])
+			f.v[k] -= 0.5 * (pressure[k+stride] - pressure[k-stride])
+		}
+	}
+	f.setBoundary(1, f.u)
+	f.setBoundary(2, f.v)
+}
+
+// setBoundary fills the border cells. Horizontal velocity (b 1) is reversed
+// at the side walls and vertical velocity (b 2) at the top and bottom, so
+// nothing flows through them; everything else is copied from the edge.
+func (f *Field) setBoundary(b int, x []float64) {
+	w, h := f.Width, f.Height
+	for j := 1; j <= h; j++ {
+		x[f.index(0, j)] = x[f.index(1, j)]
+		x[f.index(w+1, j)] = x[f.index(w, j)]
+		if b == 1 {
+			x[f.index(0, j)] = -x[f.index(0, j)]
+			x[f.index(w+1, j)] = -x[f.index(w+1, j)]
+		}
+	}
+	for i := 1; i <= w; i++ {
+		x[f.index(i, 0)] = x[f.index(i, 1)]
+		x[f.index(i, h+1)] = x[f.index(i, h)]
+		if b == 2 {
+			x[f.index(i, 0)] = -x[f.index(i, 0)]
+			x[f.index(i, h+1)] = -x[f.index(i, h+1)]
+		}
+	}
+	x[f.index(0, 0)] = 0.5 * (x[f.index(1, 0)] + x[f.index(0, 1)])
+	x[f.index(0, h+1)] = 0.5 * (x[f.index(1, h+1)] + x[f.index(0, h)])
+	x[f.index(w+1, 0)] = 0.5 * (x[f.index(w, 0)] + x[f.index(w+1, 1)])
+	x[f.index(w+1, h+1)] = 0.5 * (x[f.index(w, h+1)] + x[f.index(w+1, h)])
+}
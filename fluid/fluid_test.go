@@ -0,0 +1,106 @@
+package fluid
+
+import (
+	"math"
+	"testing"
+)
+
+func (f *Field) maxDivergence() float64 {
+	stride := f.Width + 2
+	var worst float64
+	for j := 1; j <= f.Height; j++ {
+		for i := 1; i <= f.Width; i++ {
+			k := f.index(i, j)
+			worst = math.Max(worst, math.Abs(f.u[k+1]-f.u[k-1]+f.v[k+stride]-f.v[k-stride]))
+		}
+	}
+	return worst
+}
+
+func (f *Field) totalDye() float64 {
+	var total float64
+	for j := 0; j < f.Height; j++ {
+		for i := 0; i < f.Width; i++ {
+			total += f.Dye(i, j)
+		}
+	}
+	return total
+}
+
+func TestProjectionRemovesDivergence(t *testing.T) {
+	// Fluid streaming out of a source in the middle
+	f := New(32, 24)
+	for j := 1; j <= f.Height; j++ {
+		for i := 1; i <= f.Width; i++ {
+			dx, dy := float64(i)-16.5, float64(j)-12.5
+			falloff := math.Exp(-(dx*dx + dy*dy) / 16)
+			f.u[f.index(i, j)] = dx * falloff
+			f.v[f.index(i, j)] = dy * falloff
+		}
+	}
+
+	before := f.maxDivergence()
+	f.project()
+	if after := f.maxDivergence(); after > before*0.25 {
+		t.Errorf("Divergence went from %.3f to %.3f, want a large reduction", before, after)
+	}
+}
+
+func TestFlowCarriesDye(t *testing.T) {
+	f := New(40, 20)
+	f.AddDye(10, 10, 1, 3)
+
+	centre := func() float64 {
+		var sum, weighted float64
+		for j := 0; j < f.Height; j++ {
+			for i := 0; i < f.Width; i++ {
+				sum += f.Dye(i, j)
+				weighted += f.Dye(i, j) * (float64(i) + 0.5)
+			}
+		}
+		return weighted / sum
+	}
+
+	start := centre()
+	for step := 0; step < 30; step++ {
+		f.AddVelocity(10, 10, 20, 0, 6)
+		f.Step(1.0 / 60)
+	}
+	if moved := centre() - start; moved < 2 {
+		t.Errorf("Dye centre moved %.2f cells downstream, want at least 2", moved)
+	}
+	if vx, _ := f.Velocity(12, 10); vx <= 0 {
+		t.Errorf("Velocity downstream of the push = %.3f, want positive", vx)
+	}
+}
+
+func TestDiffusionKeepsDyeInsideWalls(t *testing.T) {
+	f := New(24, 24)
+	f.Diffusion = 2
+	f.AddDye(4, 4, 1, 4)
+
+	before, peak := f.totalDye(), f.Dye(3, 3)
+	for step := 0; step < 120; step++ {
+		f.Step(1.0 / 60)
+	}
+	if after := f.totalDye(); math.Abs(after-before)/before > 0.01 {
+		t.Errorf("Total dye went from %.3f to %.3f", before, after)
+	}
+	if f.Dye(3, 3) >= peak {
+		t.Errorf("Dye did not spread: peak %.3f, now %.3f", peak, f.Dye(3, 3))
+	}
+}
+
+func TestDissipation(t *testing.T) {
+	f := New(16, 16)
+	f.Dissipation = 0.5
+	f.AddDye(8, 8, 1, 3)
+
+	before := f.totalDye()
+	for step := 0; step < 60; step++ {
+		f.Step(1.0 / 60)
+	}
+	if after := f.totalDye(); after > before*0.7 || after < before*0.5 {
+		t.Errorf("Dye after a second at 0.5 dissipation is %.3f of the start, want about 0.6", after/before)
+	}
+}
@@ -0,0 +1,159 @@
+// Package forces moves particles through fields of attractors, vortices and
+// turbulence, and keeps them within the screen.
+package forces
+
+import (
+	"fmt"
+	"math"
+)
+
+// Force accelerates a particle at (x, y). t is the time in ticks, for
+// forces that change on their own.
+type Force interface {
+	Accel(x, y, t float64) (ax, ay float64)
+}
+
+// Attractor pulls particles towards a point, or pushes them away when
+// Strength is negative. The pull is Strength at the centre and falls off
+// with the square of the distance beyond Radius.
+type Attractor struct {
+	X, Y     float64
+	Strength float64
+	Radius   float64
+}
+
+func (a *Attractor) Accel(x, y, _ float64) (float64, float64) {
+	dx, dy := a.X-x, a.Y-y
+	distance := math.Hypot(dx, dy)
+	if distance == 0 {
+		return 0, 0
+	}
+	pull := a.Strength * falloff(distance, a.Radius)
+	return dx / distance * pull, dy / distance * pull
+}
+
+// Vortex swirls particles around a point, clockwise on screen for a positive
+// Strength, falling off like an Attractor.
+type Vortex struct {
+	X, Y     float64
+	Strength float64
+	Radius   float64
+}
+
+func (v *Vortex) Accel(x, y, _ float64) (float64, float64) {
+	dx, dy := x-v.X, y-v.Y
+	distance := math.Hypot(dx, dy)
+	if distance == 0 {
+		return 0, 0
+	}
+	swirl := v.Strength * falloff(distance, v.Radius)
+	return -dy / distance * swirl, dx / distance * swirl
+}
+
+func falloff(distance, radius float64) float64 {
+	if radius <= 0 {
+		return 1
+	}
+	r := distance / radius
+	return 1 / (1 + r*r)
+}
+
+// Turbulence stirs particles with curl noise: the flow follows the contours
+// of a smoothly drifting noise field, so it swirls without gathering
+// particles into clumps.
+type Turbulence struct {
+	Strength float64
+	Scale    float64 // Size of the eddies in pixels
+	Speed    float64 // How fast the field drifts, in eddies per tick
+	noise    *Noise
+}
+
+// NewTurbulence creates turbulence whose noise comes from seed.
+func NewTurbulence(seed int64, strength, scale, speed float64) *Turbulence {
+	return &Turbulence{Strength: strength, Scale: scale, Speed: speed, noise: NewNoise(seed)}
+}
+
+func (t *Turbulence) Accel(x, y, time float64) (float64, float64) {
+	if t.Scale <= 0 {
+		return 0, 0
+	}
+	const h = 0.01 // Step for the finite differences, in eddies
+	px, py, pz := x/t.Scale, y/t.Scale, time*t.Speed
+	dx := (t.noise.At(px+h, py, pz) - t.noise.At(px-h, py, pz)) / (2 * h)
+	dy := (t.noise.At(px, py+h, pz) - t.noise.At(px, py-h, pz)) / (2 * h)
+	return dy * t.Strength, -dx * t.Strength
+}
+
+// Field is a set of forces acting together.
+type Field []Force
+
+// Accel returns the sum of the forces at (x, y).
+func (f Field) Accel(x, y, t float64) (float64, float64) {
+	var ax, ay float64
+	for _, force := range f {
+		fx, fy := force.Accel(x, y, t)
+		ax += fx
+		ay += fy
+	}
+	return ax, ay
+}
+
+// Boundary is what happens to a particle that leaves the screen.
+type Boundary int
+
+const (
+	Kill   Boundary = iota // The particle is removed
+	Wrap                   // The particle comes back on the opposite side
+	Bounce                 // The particle is reflected off the edge
+)
+
+// ParseBoundary returns the boundary called name: kill, wrap or bounce.
+func ParseBoundary(name string) (Boundary, error) {
+	switch name {
+	case "", "kill":
+		return Kill, nil
+	case "wrap":
+		return Wrap, nil
+	case "bounce":
+		return Bounce, nil
+	}
+	return Kill, fmt.Errorf("unknown boundary %q", name)
+}
+
+func (b Boundary) String() string {
+	switch b {
+	case Wrap:
+		return "wrap"
+	case Bounce:
+		return "bounce"
+	}
+	return "kill"
+}
+
+// Apply keeps a particle at (x, y) moving at (vx, vy) within a width by
+// height screen. It returns the particle's new position and velocity, and
+// false if it should be removed.
+func (b Boundary) Apply(x, y, vx, vy, width, height float64) (float64, float64, float64, float64, bool) {
+	inside := x >= 0 && x <= width && y >= 0 && y <= height
+	if inside {
+		return x, y, vx, vy, true
+	}
+
+	switch b {
+	case Wrap:
+		x = math.Mod(math.Mod(x, width)+width, width)
+		y = math.Mod(math.Mod(y, height)+height, height)
+		return x, y, vx, vy, true
+	case Bounce:
+		if x < 0 || x > width {
+			x = math.Max(0, math.Min(width, 2*math.Max(0, math.Min(width, x))-x))
+			vx = -vx
+		}
+		if y < 0 || y > height {
+			y = math.Max(0, math.Min(height, 2*math.Max(0, math.Min(height, y))-y))
+			vy = -vy
+		}
+		return x, y, vx, vy, true
+	}
+	return x, y, vx, vy, false
+}
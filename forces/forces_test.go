@@ -0,0 +1,111 @@
+package forces
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAttractorAndRepulsor(t *testing.T) {
+	attractor := &Attractor{X: 100, Y: 100, Strength: 2, Radius: 50}
+	ax, ay := attractor.Accel(150, 100, 0)
+	if ax >= 0 || math.Abs(ay) > 1e-9 {
+		t.Errorf("Attractor at (150, 100) = (%.3f, %.3f), want a pull in -x", ax, ay)
+	}
+	if want := -1.0; math.Abs(ax-want) > 1e-9 {
+		t.Errorf("Pull at one radius = %.3f, want %.3f", ax, want)
+	}
+
+	repulsor := &Attractor{X: 100, Y: 100, Strength: -2, Radius: 50}
+	if ax, _ := repulsor.Accel(150, 100, 0); ax <= 0 {
+		t.Errorf("Repulsor pushes %.3f, want a push in +x", ax)
+	}
+	if ax, ay := attractor.Accel(100, 100, 0); ax != 0 || ay != 0 {
+		t.Errorf("Force at the centre = (%.3f, %.3f), want none", ax, ay)
+	}
+}
+
+func TestVortexIsTangential(t *testing.T) {
+	vortex := &Vortex{X: 0, Y: 0, Strength: 1, Radius: 10}
+	for _, p := range [][2]float64{{10, 0}, {0, 10}, {-7, 3}} {
+		ax, ay := vortex.Accel(p[0], p[1], 0)
+		if dot := ax*p[0] + ay*p[1]; math.Abs(dot) > 1e-9 {
+			t.Errorf("Vortex at %v has radial component %.3f", p, dot)
+		}
+	}
+	// Clockwise on screen, where y points down: right of the centre moves down
+	if _, ay := vortex.Accel(10, 0, 0); ay <= 0 {
+		t.Errorf("Vortex right of the centre moves %.3f in y, want down", ay)
+	}
+}
+
+func TestTurbulenceIsDivergenceFree(t *testing.T) {
+	turbulence := NewTurbulence(1, 1, 40, 0.01)
+	const h = 0.5
+	var total, divergence float64
+	for y := 10.0; y < 200; y += 17 {
+		for x := 10.0; x < 200; x += 13 {
+			ax1, _ := turbulence.Accel(x+h, y, 5)
+			ax0, _ := turbulence.Accel(x-h, y, 5)
+			_, ay1 := turbulence.Accel(x, y+h, 5)
+			_, ay0 := turbulence.Accel(x, y-h, 5)
+			divergence += math.Abs((ax1-ax0)/(2*h) + (ay1-ay0)/(2*h))
+			ax, ay := turbulence.Accel(x, y, 5)
+			total += math.Hypot(ax, ay)
+		}
+	}
+	if total == 0 {
+		t.Fatal("Turbulence produced no force")
+	}
+	if divergence/total > 1e-3 {
+		t.Errorf("Turbulence divergence %.5f relative to its strength, want close to 0", divergence/total)
+	}
+}
+
+func TestNoiseIsSeeded(t *testing.T) {
+	a, b, c := NewNoise(1), NewNoise(1), NewNoise(2)
+	if a.At(1.3, 2.7, 0.5) != b.At(1.3, 2.7, 0.5) {
+		t.Error("Same seed gave different noise")
+	}
+	if a.At(1.3, 2.7, 0.5) == c.At(1.3, 2.7, 0.5) {
+		t.Error("Different seeds gave the same noise")
+	}
+	if n := a.At(3, 4, 5); n != 0 {
+		t.Errorf("Noise at a lattice point = %.3f, want 0", n)
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	for _, tc := range []struct {
+		boundary   Boundary
+		x, y       float64
+		vx, vy     float64
+		wantX      float64
+		wantY      float64
+		wantVX     float64
+		wantVY     float64
+		wantInside bool
+	}{
+		{Kill, 50, 50, 1, 1, 50, 50, 1, 1, true},
+		{Kill, 105, 50, 1, 0, 105, 50, 1, 0, false},
+		{Wrap, 105, -10, 1, -1, 5, 90, 1, -1, true},
+		{Bounce, 105, 50, 2, 1, 95, 50, -2, 1, true},
+		{Bounce, 20, -4, 0, -3, 20, 4, 0, 3, true},
+	} {
+		x, y, vx, vy, inside := tc.boundary.Apply(tc.x, tc.y, tc.vx, tc.vy, 100, 100)
+		if inside != tc.wantInside || (inside && (math.Abs(x-tc.wantX) > 1e-9 || math.Abs(y-tc.wantY) > 1e-9 || vx != tc.wantVX || vy != tc.wantVY)) {
+			t.Errorf("%v.Apply(%v, %v, %v, %v) = (%v, %v, %v, %v, %v)", tc.boundary, tc.x, tc.y, tc.vx, tc.vy, x, y, vx, vy, inside)
+		}
+	}
+}
+
+func TestParseBoundary(t *testing.T) {
+	for _, name := range []string{"kill", "wrap", "bounce"} {
+		b, err := ParseBoundary(name)
+		if err != nil || b.String() != name {
+			t.Errorf("ParseBoundary(%q) = %v, %v", name, b, err)
+		}
+	}
+	if _, err := ParseBoundary("stick"); err == nil {
+		t.Error("Expected an error for an unknown boundary")
+	}
+}
@@ -0,0 +1,267 @@
+// Package keymap turns held keys and buttons into named actions, with
+// bindings that can be changed from a config file.
+package keymap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Mode is when an action fires while its binding is held.
+type Mode int
+
+const (
+	Press  Mode = iota // Once, when the binding is pressed
+	Hold               // Every tick the binding is held
+	Repeat             // When pressed, then over and over after a delay, like typing
+)
+
+const (
+	defaultRepeatDelay    = 15 // Ticks before a held binding starts repeating
+	defaultRepeatInterval = 3  // Ticks between repeats
+)
+
+// Source reports how long an input has been held, in ticks, or 0 when it is
+// up. An input that went down this tick has been held for 1.
+type Source interface {
+	Duration(input string) int
+}
+
+// Canonical checks an input name and returns the one it is known by, so
+// "ctrl" and "Control" are the same input. It returns an error for names it
+// doesn't know.
+type Canonical func(name string) (string, error)
+
+// Binding is an input, with modifiers that must be held with it.
+type Binding struct {
+	Input     string
+	Modifiers []string
+}
+
+// ParseBinding reads a binding written as the input after its modifiers,
+// joined by plus signs, such as "Shift+R" or "Ctrl+Shift+F1". Names are
+// checked by canonical unless it is nil.
+func ParseBinding(s string, canonical Canonical) (Binding, error) {
+	parts := strings.Split(s, "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Binding{}, fmt.Errorf("invalid binding %q", s)
+		}
+		if canonical != nil {
+			name, err := canonical(part)
+			if err != nil {
+				return Binding{}, fmt.Errorf("invalid binding %q: %v", s, err)
+			}
+			part = name
+		}
+		parts[i] = part
+	}
+	return Binding{Input: parts[len(parts)-1], Modifiers: parts[:len(parts)-1]}, nil
+}
+
+func (b Binding) String() string {
+	return strings.Join(append(append([]string(nil), b.Modifiers...), b.Input), "+")
+}
+
+// Map holds the bindings of every action and which fired this tick.
+type Map struct {
+	RepeatDelay    int
+	RepeatInterval int
+	actions        []string
+	modes          map[string]Mode
+	bindings       map[string][]Binding
+	fired          map[string]bool
+	matches        []match
+}
+
+type match struct {
+	action    string
+	binding   Binding
+	duration  int
+	modifiers int
+}
+
+// New creates an empty map.
+func New() *Map {
+	return &Map{
+		RepeatDelay:    defaultRepeatDelay,
+		RepeatInterval: defaultRepeatInterval,
+		modes:          make(map[string]Mode),
+		bindings:       make(map[string][]Binding),
+		fired:          make(map[string]bool),
+	}
+}
+
+// Add defines an action with its default bindings.
+func (m *Map) Add(action string, mode Mode, bindings ...Binding) {
+	if _, ok := m.modes[action]; !ok {
+		m.actions = append(m.actions, action)
+	}
+	m.modes[action] = mode
+	m.bindings[action] = bindings
+}
+
+// Bind replaces the bindings of an action.
+func (m *Map) Bind(action string, bindings []Binding) error {
+	if _, ok := m.modes[action]; !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	m.bindings[action] = bindings
+	return nil
+}
+
+// Bindings returns the bindings of an action.
+func (m *Map) Bindings(action string) []Binding {
+	return m.bindings[action]
+}
+
+// Actions returns the names of the actions in the order they were added.
+func (m *Map) Actions() []string {
+	return m.actions
+}
+
+// Load rebinds the actions named in a JSON object of action names to lists
+// of bindings, leaving the rest as they are. An empty list unbinds an action.
+func (m *Map) Load(r io.Reader, canonical Canonical) error {
+	var config map[string][]string
+	if err := json.NewDecoder(r).Decode(&config); err != nil {
+		return err
+	}
+	for action, written := range config {
+		bindings := make([]Binding, 0, len(written))
+		for _, s := range written {
+			b, err := ParseBinding(s, canonical)
+			if err != nil {
+				return fmt.Errorf("%s: %v", action, err)
+			}
+			bindings = append(bindings, b)
+		}
+		if err := m.Bind(action, bindings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update works out which actions fire this tick. When bindings of the same
+// input match, only those with the most modifiers fire, so Shift+R doesn't
+// also fire R.
+func (m *Map) Update(source Source) {
+	clear(m.fired)
+	m.matches = m.matches[:0]
+	for _, action := range m.actions {
+		for _, b := range m.bindings[action] {
+			duration := source.Duration(b.Input)
+			if duration == 0 || !held(source, b.Modifiers) {
+				continue
+			}
+			m.matches = append(m.matches, match{action: action, binding: b, duration: duration, modifiers: len(b.Modifiers)})
+		}
+	}
+
+	for _, mt := range m.matches {
+		if m.shadowed(mt) || !m.fires(m.modes[mt.action], mt.duration) {
+			continue
+		}
+		m.fired[mt.action] = true
+	}
+}
+
+// shadowed reports whether a binding of the same input with more modifiers
+// also matched.
+func (m *Map) shadowed(mt match) bool {
+	for _, other := range m.matches {
+		if other.binding.Input == mt.binding.Input && other.modifiers > mt.modifiers {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Map) fires(mode Mode, duration int) bool {
+	switch mode {
+	case Hold:
+		return true
+	case Repeat:
+		if duration == 1 {
+			return true
+		}
+		return duration > m.RepeatDelay && (duration-m.RepeatDelay)%max(1, m.RepeatInterval) == 0
+	}
+	return duration == 1
+}
+
+// Fired reports whether an action fired this tick.
+func (m *Map) Fired(action string) bool {
+	return m.fired[action]
+}
+
+func held(source Source, inputs []string) bool {
+	for _, input := range inputs {
+		if source.Duration(input) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Entry is an action as it is listed in the help.
+type Entry struct {
+	Action string
+	Group  string // Line of the listing it appears on
+	Label  string
+}
+
+// Help lists the bindings of entries, one line per group in the order of
+// groups, with each binding written by label. Unbound actions and groups
+// with nothing bound are left out.
+func (m *Map) Help(groups []string, entries []Entry, label func(Binding) string) []string {
+	var lines []string
+	for _, group := range groups {
+		var listed []string
+		for _, e := range entries {
+			bindings := m.bindings[e.Action]
+			if e.Group != group || len(bindings) == 0 {
+				continue
+			}
+			written := make([]string, len(bindings))
+			for i, b := range bindings {
+				written[i] = label(b)
+			}
+			listed = append(listed, fmt.Sprintf("%s (%s)", strings.Join(written, "/"), e.Label))
+		}
+		if len(listed) > 0 {
+			lines = append(lines, fmt.Sprintf("%-10s %s", group+":", strings.Join(listed, ", ")))
+		}
+	}
+	return lines
+}
+
+// Held is a Source told of presses and releases, such as those recorded in
+// a session, rather than asking the devices.
+type Held map[string]int
+
+// Press marks input as going down on the next tick.
+func (h Held) Press(input string) {
+	h[input] = 0
+}
+
+// Release marks input as up.
+func (h Held) Release(input string) {
+	delete(h, input)
+}
+
+// Tick moves time on, so every input down has been held a tick longer.
+func (h Held) Tick() {
+	for input := range h {
+		h[input]++
+	}
+}
+
+// Duration returns how many ticks input has been held.
+func (h Held) Duration(input string) int {
+	return h[input]
+}
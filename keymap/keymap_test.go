@@ -0,0 +1,235 @@
+package keymap
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// keys is a Source that ticks like a keyboard: held inputs count up.
+type keys map[string]int
+
+func (k keys) Duration(input string) int { return k[input] }
+
+func (k keys) tick(held ...string) {
+	for input := range k {
+		if !contains(held, input) {
+			delete(k, input)
+		}
+	}
+	for _, input := range held {
+		k[input]++
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParse(t *testing.T, s string) Binding {
+	t.Helper()
+	b, err := ParseBinding(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestModes(t *testing.T) {
+	m := New()
+	m.RepeatDelay, m.RepeatInterval = 5, 2
+	m.Add("press", Press, mustParse(t, "P"))
+	m.Add("hold", Hold, mustParse(t, "H"))
+	m.Add("repeat", Repeat, mustParse(t, "R"))
+
+	k := keys{}
+	counts := map[string]int{}
+	for i := 0; i < 10; i++ {
+		k.tick("P", "H", "R")
+		m.Update(k)
+		for _, action := range m.Actions() {
+			if m.Fired(action) {
+				counts[action]++
+			}
+		}
+	}
+	// Repeat fires on the press, then on ticks 7 and 9 after the delay of 5
+	want := map[string]int{"press": 1, "hold": 10, "repeat": 3}
+	for action, n := range want {
+		if counts[action] != n {
+			t.Errorf("%s fired %d times in 10 ticks, want %d", action, counts[action], n)
+		}
+	}
+
+	// Pressing again fires again
+	k.tick()
+	k.tick("P")
+	m.Update(k)
+	if !m.Fired("press") {
+		t.Error("A second press didn't fire")
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	m := New()
+	m.Add("less", Press, mustParse(t, "R"))
+	m.Add("more", Press, mustParse(t, "Shift+R"))
+	m.Add("both", Press, mustParse(t, "Ctrl+Shift+F1"))
+
+	tests := []struct {
+		held []string
+		want []string
+	}{
+		{[]string{"R"}, []string{"less"}},
+		{[]string{"Shift", "R"}, []string{"more"}},
+		{[]string{"Ctrl", "R"}, []string{"less"}}, // Nothing bound to Ctrl+R
+		{[]string{"Shift", "F1"}, nil},
+		{[]string{"Ctrl", "Shift", "F1"}, []string{"both"}},
+	}
+	for _, tt := range tests {
+		k := keys{}
+		k.tick(tt.held...)
+		m.Update(k)
+		var fired []string
+		for _, action := range m.Actions() {
+			if m.Fired(action) {
+				fired = append(fired, action)
+			}
+		}
+		if fmt.Sprint(fired) != fmt.Sprint(tt.want) {
+			t.Errorf("Holding %v fired %v, want %v", tt.held, fired, tt.want)
+		}
+	}
+}
+
+func TestParseBinding(t *testing.T) {
+	canonical := func(name string) (string, error) {
+		switch strings.ToLower(name) {
+		case "ctrl", "control":
+			return "Control", nil
+		case "shift":
+			return "Shift", nil
+		case "a":
+			return "A", nil
+		}
+		return "", fmt.Errorf("unknown input %q", name)
+	}
+	b, err := ParseBinding("ctrl + shift+a", canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "Control+Shift+A" {
+		t.Errorf("Parsed %q, want Control+Shift+A", s)
+	}
+	for _, bad := range []string{"", "Shift+", "Ctrl+Q"} {
+		if _, err := ParseBinding(bad, canonical); err == nil {
+			t.Errorf("Expected an error parsing %q", bad)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	m := New()
+	m.Add("next", Press, mustParse(t, "BracketRight"))
+	m.Add("hud", Press, mustParse(t, "Space"))
+
+	if err := m.Load(strings.NewReader(`{"next": ["N", "Shift+Right"], "hud": []}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(m.Bindings("next")); got != "[N Shift+Right]" {
+		t.Errorf("next is bound to %s", got)
+	}
+	if len(m.Bindings("hud")) != 0 {
+		t.Errorf("hud is still bound to %v", m.Bindings("hud"))
+	}
+
+	if err := m.Load(strings.NewReader(`{"jump": ["J"]}`), nil); err == nil {
+		t.Error("Expected an error binding an unknown action")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	m := New()
+	m.Add("smooth", Press, mustParse(t, "Digit1"))
+	m.Add("bezier", Press, mustParse(t, "Digit2"))
+	m.Add("previous", Press, mustParse(t, "BracketLeft"), mustParse(t, "GamepadLB"))
+	m.Add("hud", Press, mustParse(t, "Space"))
+	if err := m.Load(strings.NewReader(`{"smooth": ["Ctrl+Q", "GamepadStart"], "hud": []}`), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []Entry{
+		{Action: "smooth", Group: "Waveforms", Label: "Smooth"},
+		{Action: "bezier", Group: "Waveforms", Label: "Bezier"},
+		{Action: "previous", Group: "Presets", Label: "Previous"},
+		{Action: "hud", Group: "Display", Label: "HUD"},
+	}
+	label := func(b Binding) string {
+		return strings.TrimPrefix(strings.ReplaceAll(b.String(), "BracketLeft", "["), "Digit")
+	}
+	got := m.Help([]string{"Presets", "Display", "Waveforms", "Setlist"}, entries, label)
+	want := []string{
+		"Presets:   [/GamepadLB (Previous)",
+		"Waveforms: Ctrl+Q/GamepadStart (Smooth), 2 (Bezier)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Help listing is\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestHeld(t *testing.T) {
+	m := New()
+	m.RepeatDelay, m.RepeatInterval = 5, 2
+	m.Add("blob", Press, mustParse(t, "Digit3"))
+	m.Add("bezier", Press, mustParse(t, "Digit2"))
+	m.Add("filled", Press, mustParse(t, "Shift+Digit2"))
+	m.Add("more", Repeat, mustParse(t, "Shift+R"))
+	m.Add("pilot", Press, mustParse(t, "GamepadA"))
+
+	// hold presses inputs, keeps them down for ticks and releases them,
+	// counting the actions fired
+	held := Held{}
+	hold := func(ticks int, inputs ...string) map[string]int {
+		for _, input := range inputs {
+			held.Press(input)
+		}
+		fired := make(map[string]int)
+		for i := 0; i < ticks; i++ {
+			held.Tick()
+			m.Update(held)
+			for _, action := range m.Actions() {
+				if m.Fired(action) {
+					fired[action]++
+				}
+			}
+		}
+		for _, input := range inputs {
+			held.Release(input)
+		}
+		return fired
+	}
+
+	tests := []struct {
+		ticks  int
+		inputs []string
+		want   map[string]int
+	}{
+		{1, []string{"Digit3"}, map[string]int{"blob": 1}},
+		{1, []string{"Shift", "Digit2"}, map[string]int{"filled": 1}},
+		{m.RepeatDelay + 2*m.RepeatInterval, []string{"Shift", "R"}, map[string]int{"more": 3}},
+		{30, []string{"GamepadA"}, map[string]int{"pilot": 1}},
+	}
+	for _, tt := range tests {
+		if got := hold(tt.ticks, tt.inputs...); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Holding %v for %d ticks fired %v, want %v", tt.inputs, tt.ticks, got, tt.want)
+		}
+	}
+	if len(held) != 0 {
+		t.Errorf("Inputs %v are still held after being released", held)
+	}
+}
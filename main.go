@@ -1,25 +1,66 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/idroz/mezmer/visualiser"
 )
 
 func main() {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	var opts visualiser.Options
+	flag.StringVar(&opts.Input, "input", "device", "audio input: device (OP-XY/OP-Z), system (what this machine is playing), generator, replay, udp, rtp, - for raw PCM on stdin, or the path of a named pipe")
+	flag.StringVar(&opts.Signal, "signal", "saw:110*0.5+drums:120", "signal for the generator input, e.g. sine:440+pink*0.2, chirp:20-8000/5, drums:120")
+	flag.Int64Var(&opts.Seed, "seed", 0, "seed for all randomness, so identical audio renders identically (0 picks one from the clock)")
+	flag.StringVar(&opts.Presets, "presets", "", "JSON file of presets to step through with [ and ]")
+	flag.StringVar(&opts.Overlays, "overlays", "", "JSON file of titles and logos to cue with F1 to F12")
+	flag.StringVar(&opts.Keymap, "keymap", "", "JSON file rebinding key actions to other keys and gamepad buttons")
+	flag.StringVar(&opts.Setlist, "setlist", "", "JSON file of tracks to play through with PageUp and PageDown")
+	flag.StringVar(&opts.MIDI, "midi", "", "Raw MIDI device, such as /dev/snd/midiC1D0 on Linux, whose program changes pick setlist tracks; elsewhere post track actions to the control surface")
+	flag.StringVar(&opts.Record, "record", "", "record the audio and controls of this session to a file")
+	flag.StringVar(&opts.Session, "session", "", "session file played by the replay input")
+	flag.StringVar(&opts.Render, "render", "", "render a replayed session to PNG frames in this directory instead of playing it live")
+	flag.IntVar(&opts.Width, "width", 3840, "width of rendered frames")
+	flag.IntVar(&opts.Height, "height", 2160, "height of rendered frames")
+	flag.StringVar(&opts.Listen, "listen", ":5004", "address the udp and rtp inputs listen on")
+	flag.StringVar(&opts.Format, "format", "", "sample format of streamed input: s16le, s16be, s24le, s24be, f32le, l16 or l24 (default s16le, l16 for rtp)")
+	flag.IntVar(&opts.Channels, "channels", 1, "channels of streamed input, mixed down to mono")
+	flag.IntVar(&opts.Rate, "rate", 44100, "sample rate of streamed input")
+	flag.DurationVar(&opts.Latency, "latency", 60*time.Millisecond, "audio buffered by network and stream inputs")
+	fullscreen := flag.Bool("fullscreen", false, "start in fullscreen (toggle with F; default: as the last run ended)")
+	flag.IntVar(&opts.Monitor, "monitor", -1, "monitor to open on, 0 for the primary (default: the one used last time)")
+	flag.BoolVar(&opts.HideCursor, "hide-cursor", false, "hide the mouse cursor over the window (toggle with C)")
+	flag.BoolVar(&opts.Projector, "projector", false, "borderless, always-on-top window covering the whole monitor")
+	flag.BoolVar(&opts.VSync, "vsync", true, "sync frames to the display; disable to draw as fast as possible")
+	flag.IntVar(&opts.TPS, "tps", 60, "updates per second; timed changes such as transitions and the autopilot keep their length in seconds, replays use the recorded rate")
+	flag.BoolVar(&opts.Autopilot, "autopilot", false, "change presets, patterns and palettes by themselves with the music (toggle with A)")
+	flag.DurationVar(&opts.Dwell, "dwell", 30*time.Second, "shortest time the autopilot keeps a scene")
+	flag.StringVar(&opts.AutopilotPresets, "autopilot-presets", "", "comma separated presets the autopilot picks from (default all)")
+	flag.StringVar(&opts.Control, "control", "", "serve a performer control surface on this address, e.g. localhost:8080, and keep the HUD off the output")
+	flag.StringVar(&opts.ControlToken, "control-token", "", "token the control surface asks for, required unless -control is a loopback address")
+	flag.Parse()
 
+	// Only an explicit -fullscreen overrides the state saved by the last run
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "fullscreen" {
+			opts.Fullscreen = fullscreen
+		}
+	})
+
+	// Interrupting ends the visualiser loop normally, so the session and window placement are saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-sigChan
+		<-ctx.Done()
+		stop() // A second interrupt kills the program as usual
 		log.Println("Exiting Visualizer loop...")
-		os.Exit(1) // Terminate the program gracefully
 	}()
 
-	err := visualiser.RunMezmer()
+	err := visualiser.RunMezmer(ctx, opts)
 	if err != nil {
 		log.Fatalf("Failed to start Mezmer: %v", err)
 	}
@@ -0,0 +1,144 @@
+// Package midi decodes MIDI messages from a raw byte stream, such as a Linux
+// ALSA raw MIDI device (/dev/snd/midiC1D0).
+package midi
+
+import (
+	"bufio"
+	"io"
+)
+
+// Kinds of channel message, the high nibble of the status byte.
+const (
+	NoteOff         = 0x80
+	NoteOn          = 0x90
+	PolyPressure    = 0xA0
+	ControlChange   = 0xB0
+	ProgramChange   = 0xC0
+	ChannelPressure = 0xD0
+	PitchBend       = 0xE0
+)
+
+// Message is one MIDI message.
+type Message struct {
+	Status byte
+	Data   [2]byte
+}
+
+// Kind returns the kind of a channel message, or the whole status byte for
+// system messages.
+func (m Message) Kind() byte {
+	if m.Status >= 0xF0 {
+		return m.Status
+	}
+	return m.Status & 0xF0
+}
+
+// Channel returns the channel of a channel message, from 0 to 15.
+func (m Message) Channel() int {
+	return int(m.Status & 0x0F)
+}
+
+// Decoder reads messages from a stream, following running status and
+// skipping system exclusive messages.
+type Decoder struct {
+	r       *bufio.Reader
+	running byte // Status of the last channel message, reused when a message starts with data
+}
+
+// NewDecoder creates a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next complete message.
+func (d *Decoder) Next() (Message, error) {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return Message{}, err
+		}
+
+		switch {
+		case b >= 0xF8:
+			// Real-time messages can arrive anywhere, even inside another message
+			return Message{Status: b}, nil
+		case b == 0xF0:
+			if err := d.skipSysEx(); err != nil {
+				return Message{}, err
+			}
+			d.running = 0
+			continue
+		case b >= 0xF1:
+			d.running = 0
+			return d.read(Message{Status: b}, 0, systemLength(b))
+		case b >= 0x80:
+			d.running = b
+			return d.read(Message{Status: b}, 0, channelLength(b))
+		case d.running != 0:
+			// Running status: b is the first data byte
+			m := Message{Status: d.running}
+			m.Data[0] = b
+			if channelLength(d.running) == 1 {
+				return m, nil
+			}
+			return d.read(m, 1, 2)
+		}
+		// Stray data byte with no status to run on
+	}
+}
+
+// read fills in the data bytes of m from index from up to to.
+func (d *Decoder) read(m Message, from, to int) (Message, error) {
+	for i := from; i < to; {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return Message{}, err
+		}
+		if b >= 0xF8 {
+			continue // Real-time bytes interleaved with data are dropped
+		}
+		if b >= 0x80 {
+			// A new status cuts the message short, so start over from it
+			d.r.UnreadByte()
+			return d.Next()
+		}
+		m.Data[i] = b
+		i++
+	}
+	return m, nil
+}
+
+func (d *Decoder) skipSysEx() error {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == 0xF7 {
+			return nil
+		}
+		if b >= 0x80 && b < 0xF8 {
+			// An unterminated message ends at the next status
+			d.r.UnreadByte()
+			return nil
+		}
+	}
+}
+
+func channelLength(status byte) int {
+	switch status & 0xF0 {
+	case ProgramChange, ChannelPressure:
+		return 1
+	}
+	return 2
+}
+
+func systemLength(status byte) int {
+	switch status {
+	case 0xF1, 0xF3:
+		return 1
+	case 0xF2:
+		return 2
+	}
+	return 0
+}
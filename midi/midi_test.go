@@ -0,0 +1,69 @@
+package midi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func decodeAll(t *testing.T, data []byte) []Message {
+	t.Helper()
+	d := NewDecoder(bytes.NewReader(data))
+	var messages []Message
+	for {
+		m, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	messages := decodeAll(t, []byte{
+		0xC3, 0x05, // Program change 5 on the fourth channel
+		0x07,                   // Running status: program change 7
+		0x90, 0x3C, 0x64, 0x40, // Note on, then running status with its velocity missing
+		0xF0, 0x7E, 0x00, 0xF7, // System exclusive, skipped
+		0xB0, 0x07, 0xF8, 0x50, // Control change with a clock tick in the middle
+		0x11, // Running status cut off by the end of the stream
+	})
+
+	want := []Message{
+		{Status: 0xC3, Data: [2]byte{5}},
+		{Status: 0xC3, Data: [2]byte{7}},
+		{Status: 0x90, Data: [2]byte{0x3C, 0x64}},
+		{Status: 0xB0, Data: [2]byte{0x07, 0x50}},
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("Decoded %v, want %v", messages, want)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("Message %d = %v, want %v", i, messages[i], want[i])
+		}
+	}
+	if m := messages[0]; m.Kind() != ProgramChange || m.Channel() != 3 {
+		t.Errorf("First message is kind %#x on channel %d, want a program change on channel 3", m.Kind(), m.Channel())
+	}
+}
+
+func TestRealTimeAndSystemMessages(t *testing.T) {
+	messages := decodeAll(t, []byte{0xF8, 0xF2, 0x10, 0x20, 0x30})
+	want := []Message{{Status: 0xF8}, {Status: 0xF2, Data: [2]byte{0x10, 0x20}}}
+	if len(messages) != len(want) || messages[0] != want[0] || messages[1] != want[1] {
+		t.Errorf("Decoded %v, want %v, with the data after the song position dropped", messages, want)
+	}
+}
+
+func TestInterruptedMessage(t *testing.T) {
+	// A note on cut short by a program change
+	messages := decodeAll(t, []byte{0x90, 0x3C, 0xC0, 0x02})
+	if len(messages) != 1 || messages[0] != (Message{Status: 0xC0, Data: [2]byte{2}}) {
+		t.Errorf("Decoded %v, want only the program change", messages)
+	}
+}
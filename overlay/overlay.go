@@ -0,0 +1,303 @@
+// Package overlay loads titles and logos to show over the visuals, and
+// moves them along their timelines as they are cued on and off.
+package overlay
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/idroz/mezmer/timeline"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Overlay is a title or logo shown over the visuals, cued on and off
+// while playing.
+type Overlay struct {
+	Name        string                    `json:"name"`
+	Text        string                    `json:"text"`    // Text to show, on several lines if it has newlines
+	Font        string                    `json:"font"`    // TTF or OTF file for the text, Go Regular when empty
+	Size        float64                   `json:"size"`    // Text size in points
+	Image       string                    `json:"image"`   // PNG, JPEG, GIF or SVG to show instead of text
+	X           float64                   `json:"x"`       // Anchor as a fraction of the screen width
+	Y           float64                   `json:"y"`       // Anchor as a fraction of the screen height
+	Align       string                    `json:"align"`   // left, center or right of the anchor
+	Color       string                    `json:"color"`   // #rrggbb tint, white when empty
+	Scale       *float64                  `json:"scale"`   // Size relative to a 720-pixel-high screen, 1 when unset
+	Opacity     *float64                  `json:"opacity"` // 1 when unset
+	Pulse       string                    `json:"pulse"`   // Audio feature the overlay swells with, as for force binds
+	PulseAmount float64                   `json:"pulseAmount"`
+	Show        bool                      `json:"show"`     // Shown from the start
+	Duration    float64                   `json:"duration"` // Seconds shown once cued, until cued again when zero
+	Fade        float64                   `json:"fade"`     // Seconds to fade in and out
+	Animate     map[string]timeline.Track `json:"animate"`  // Curves for x, y, scale and opacity from when it is cued
+}
+
+// Layer is an overlay loaded and ready to draw.
+type Layer struct {
+	Overlay
+	Source  image.Image // Rasterised text or the loaded image
+	Tint    color.RGBA
+	rate    float64 // Ticks per second
+	cuedAt  int64   // Tick the overlay was cued, -1 while hidden
+	until   float64 // Seconds after the cue it starts fading out, 0 to use the duration
+	x, y    float64 // Where and how to draw it this tick
+	scale   float64
+	opacity float64
+}
+
+// Load reads a JSON array of overlays from path, loading their fonts and
+// images relative to it.
+func Load(path string, updateRate float64) ([]*Layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var overlays []Overlay
+	if err := json.Unmarshal(data, &overlays); err != nil {
+		return nil, fmt.Errorf("invalid overlay file %s: %v", path, err)
+	}
+	layers := make([]*Layer, 0, len(overlays))
+	for i, o := range overlays {
+		if o.Name == "" {
+			o.Name = fmt.Sprintf("Overlay %d", i+1)
+		}
+		layer, err := New(o, filepath.Dir(path), updateRate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid overlay %s in %s: %v", o.Name, path, err)
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// New loads the font or image of o, relative to dir, ready to be cued
+// at updateRate ticks per second.
+func New(o Overlay, dir string, updateRate float64) (*Layer, error) {
+	for name, track := range o.Animate {
+		switch name {
+		case "x", "y", "scale", "opacity":
+		default:
+			return nil, fmt.Errorf("cannot animate %q", name)
+		}
+		if err := track.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	switch o.Align {
+	case "", "left", "center", "right":
+	default:
+		return nil, fmt.Errorf("unknown alignment %q", o.Align)
+	}
+	tint, err := parseColor(o.Color)
+	if err != nil {
+		return nil, err
+	}
+
+	layer := &Layer{Overlay: o, Tint: tint, rate: updateRate, cuedAt: -1}
+	if o.Show {
+		layer.cuedAt = 0
+	}
+	switch {
+	case o.Image != "" && o.Text != "":
+		return nil, fmt.Errorf("an overlay shows either text or an image")
+	case o.Image != "":
+		file, err := os.Open(resolvePath(dir, o.Image))
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if strings.EqualFold(filepath.Ext(o.Image), ".svg") {
+			layer.Source, err = rasteriseSVG(file)
+		} else {
+			layer.Source, _, err = image.Decode(file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Image, err)
+		}
+	case o.Text != "":
+		fontData := goregular.TTF
+		if o.Font != "" {
+			if fontData, err = os.ReadFile(resolvePath(dir, o.Font)); err != nil {
+				return nil, err
+			}
+		}
+		if layer.Source, err = rasteriseText(o.Text, fontData, o.Size, o.Align); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Font, err)
+		}
+	default:
+		return nil, fmt.Errorf("an overlay needs text or an image")
+	}
+	return layer, nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// parseColor reads a #rrggbb colour, white when empty.
+func parseColor(s string) (color.RGBA, error) {
+	if s == "" {
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}, nil
+	}
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(s, "#%02x%02x%02x", &r, &g, &b); err != nil || len(s) != 7 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q, want #rrggbb", s)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}, nil
+}
+
+// rasteriseText draws text in white at size points, aligning its lines to
+// each other.
+func rasteriseText(text string, fontData []byte, size float64, align string) (image.Image, error) {
+	if size <= 0 {
+		size = 32
+	}
+	parsed, err := opentype.Parse(fontData)
+	if err != nil {
+		return nil, err
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	lines := strings.Split(text, "\n")
+	metrics := face.Metrics()
+	lineHeight := metrics.Height.Ceil()
+	width := 0
+	for _, line := range lines {
+		width = max(width, font.MeasureString(face, line).Ceil())
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, lineHeight*len(lines)))
+
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(color.White), Face: face}
+	for i, line := range lines {
+		x := 0
+		switch align {
+		case "", "center":
+			x = (width - font.MeasureString(face, line).Ceil()) / 2
+		case "right":
+			x = width - font.MeasureString(face, line).Ceil()
+		}
+		drawer.Dot = fixed.P(x, i*lineHeight+metrics.Ascent.Ceil())
+		drawer.DrawString(line)
+	}
+	return img, nil
+}
+
+// rasteriseSVG draws an SVG at the size of its view box, as a PNG of the
+// same size would be shown.
+func rasteriseSVG(r io.Reader) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(r, oksvg.StrictErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	width, height := int(math.Ceil(icon.ViewBox.W)), int(math.Ceil(icon.ViewBox.H))
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("no view box size")
+	}
+	icon.SetTarget(0, 0, float64(width), float64(height))
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(width, height, scanner), 1)
+	return img, nil
+}
+
+// Cue shows the overlay at tick, or starts it fading out if it is already
+// showing.
+func (l *Layer) Cue(tick int64) {
+	if l.leaving(tick) {
+		l.cuedAt, l.until = tick, 0
+	} else {
+		l.until = math.Max(float64(tick-l.cuedAt)/l.rate, 1/l.rate)
+	}
+}
+
+// Show shows the overlay at tick, leaving it be if it is already showing.
+func (l *Layer) Show(tick int64) {
+	if l.leaving(tick) {
+		l.cuedAt, l.until = tick, 0
+	}
+}
+
+// Showing reports whether the overlay is on screen, fading included.
+func (l *Layer) Showing() bool {
+	return l.cuedAt >= 0
+}
+
+// leaving reports whether the overlay is hidden or on its way out at tick.
+func (l *Layer) leaving(tick int64) bool {
+	until := l.until
+	if until == 0 {
+		until = l.Duration
+	}
+	return l.cuedAt < 0 || (until > 0 && float64(tick-l.cuedAt)/l.rate >= until)
+}
+
+// Update moves a showing overlay along its timeline to tick, swelling it
+// by pulse, the level of its pulse feature between 0 and 1.
+func (l *Layer) Update(tick int64, pulse float64) {
+	if l.cuedAt < 0 {
+		return
+	}
+
+	elapsed := float64(tick-l.cuedAt) / l.rate
+	until := l.until
+	if until == 0 {
+		until = l.Duration
+	}
+	level, on := timeline.Envelope(elapsed, l.Fade, until)
+	if !on {
+		l.cuedAt, l.until = -1, 0
+		return
+	}
+
+	l.x = l.animated("x", elapsed, l.X)
+	l.y = l.animated("y", elapsed, l.Y)
+	l.scale = l.animated("scale", elapsed, valueOr(l.Scale, 1))
+	l.scale *= 1 + l.PulseAmount*pulse
+	l.opacity = l.animated("opacity", elapsed, valueOr(l.Opacity, 1)) * level
+}
+
+// Placement returns where the overlay is anchored this tick, as fractions
+// of the screen, with its scale and opacity.
+func (l *Layer) Placement() (x, y, scale, opacity float64) {
+	return l.x, l.y, l.scale, l.opacity
+}
+
+// animated returns the named property at elapsed seconds after the cue,
+// following its curve if it has one.
+func (o *Overlay) animated(name string, elapsed, value float64) float64 {
+	if track, ok := o.Animate[name]; ok && len(track) > 0 {
+		return track.At(elapsed)
+	}
+	return value
+}
+
+func valueOr(value *float64, fallback float64) float64 {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
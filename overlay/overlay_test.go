@@ -0,0 +1,138 @@
+package overlay
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/idroz/mezmer/timeline"
+)
+
+const testRate = 60 // Ticks per second
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "overlays.json")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"name": "Artist", "text": "Someone\nLive", "size": 48, "align": "left", "color": "#ff8000"}]`)
+	overlays, err := Load(path, testRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := overlays[0].Source.Bounds(); bounds.Dx() == 0 || bounds.Dy() < 2*48 {
+		t.Errorf("Two lines of 48pt text rasterised to %v", bounds)
+	}
+	if tint := overlays[0].Tint; tint.R != 255 || tint.G != 128 || tint.B != 0 {
+		t.Errorf("Tint = %v, want #ff8000", tint)
+	}
+
+	logo := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 40 20"><rect x="20" width="20" height="20" fill="#00ff00"/></svg>`
+	if err := os.WriteFile(filepath.Join(dir, "logo.svg"), []byte(logo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	write(`[{"name": "Logo", "image": "logo.svg"}]`)
+	if overlays, err = Load(path, testRate); err != nil {
+		t.Fatal(err)
+	}
+	source := overlays[0].Source
+	if bounds := source.Bounds(); bounds.Dx() != 40 || bounds.Dy() != 20 {
+		t.Errorf("A 40 by 20 SVG rasterised to %v", bounds)
+	}
+	if _, _, _, a := source.At(10, 10).RGBA(); a != 0 {
+		t.Errorf("The left of the SVG is drawn, want it transparent")
+	}
+	if r, g, _, a := source.At(30, 10).RGBA(); r != 0 || g != 0xffff || a != 0xffff {
+		t.Errorf("The right of the SVG is %v, want green", source.At(30, 10))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.svg"), []byte("<svg"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{
+		`[{"image": "missing.svg"}]`,
+		`[{"image": "broken.svg"}]`,
+		`[{"image": "missing.png"}]`,
+		`[{"text": "A", "image": "logo.png"}]`,
+		`[{}]`,
+		`[{"text": "A", "color": "orange"}]`,
+		`[{"text": "A", "align": "justify"}]`,
+		`[{"text": "A", "animate": {"rotation": [{"time": 0, "value": 1}]}}]`,
+		`[{"text": "A", "animate": {"x": [{"time": 0, "value": 1, "ease": "wobble"}]}}]`,
+	} {
+		write(bad)
+		if _, err := Load(path, testRate); err == nil {
+			t.Errorf("Expected an error loading %s", bad)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	layer, err := New(Overlay{Name: "Title", Text: "Title", Fade: 1, Duration: 3, Animate: map[string]timeline.Track{
+		"y": {{Time: 0, Value: 1}, {Time: 1, Value: 0.5, Ease: "out"}},
+	}}, "", testRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tick int64
+	run := func(seconds float64) {
+		for i := 0; i < int(seconds*testRate); i++ {
+			tick++
+			layer.Update(tick, 0)
+		}
+	}
+
+	layer.Cue(tick)
+	run(0.5)
+	if math.Abs(layer.opacity-0.5) > 0.02 || layer.y <= 0.5 {
+		t.Errorf("Half a second in: opacity %.2f and y %.2f, want 0.5 and still rising", layer.opacity, layer.y)
+	}
+	run(1)
+	if layer.opacity != 1 || layer.y != 0.5 {
+		t.Errorf("After the fade: opacity %.2f and y %.2f, want 1 and 0.5", layer.opacity, layer.y)
+	}
+	run(3)
+	if layer.Showing() {
+		t.Error("Overlay still showing after its duration and fade")
+	}
+
+	// Cueing a showing overlay fades it out early
+	layer.Cue(tick)
+	run(1.5)
+	layer.Cue(tick)
+	run(0.5)
+	if math.Abs(layer.opacity-0.5) > 0.02 {
+		t.Errorf("Half way through fading out: opacity %.2f, want 0.5", layer.opacity)
+	}
+	run(0.6)
+	if layer.Showing() {
+		t.Error("Overlay still showing after being hidden")
+	}
+}
+
+func TestShowAndPulse(t *testing.T) {
+	amount := 0.5
+	layer, err := New(Overlay{Name: "Logo", Text: "Logo", Pulse: "bass", PulseAmount: amount}, "", testRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Showing a showing overlay leaves it up, where cueing it again would hide it
+	layer.Show(1)
+	layer.Show(2)
+	layer.Update(3, 1)
+	if _, _, scale, opacity := layer.Placement(); opacity != 1 || scale != 1+amount {
+		t.Errorf("Shown twice at full pulse: scale %.2f and opacity %.2f, want %.2f and 1", scale, opacity, 1+amount)
+	}
+	layer.Cue(4)
+	layer.Update(5, 0)
+	if layer.Showing() {
+		t.Error("Overlay still showing after being cued again without a fade")
+	}
+}
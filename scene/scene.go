@@ -0,0 +1,136 @@
+// Package scene describes how a scene looks and blends one look into the
+// next when the scene changes.
+package scene
+
+import (
+	"fmt"
+
+	"github.com/idroz/mezmer/timeline"
+)
+
+const (
+	defaultDuration = 1.0 // Seconds a transition takes when not given
+	beatWait        = 2.0 // Seconds to wait for a beat before changing anyway
+)
+
+// Kinds are the ways of moving between looks. A cut is instant, the rest
+// blend the old scene into the new one.
+var Kinds = []string{"cut", "fade", "dissolve", "wipe", "zoom"}
+
+var ease, _ = timeline.ParseEase("in-out")
+
+// Look is everything that decides how a scene is drawn.
+type Look struct {
+	WaveForm         string
+	Fill             bool
+	Pattern          string
+	Red, Green, Blue int
+	Fluid            bool
+	Tilt             float64
+	Scroll           float64
+}
+
+// SameRenderers reports whether two looks draw with the same waveform and
+// pattern, so one can morph into the other.
+func (l Look) SameRenderers(other Look) bool {
+	return l.WaveForm == other.WaveForm && l.Fill == other.Fill && l.Pattern == other.Pattern && l.Fluid == other.Fluid
+}
+
+// ValidateKind checks that kind is a known transition.
+func ValidateKind(kind string) error {
+	if kind == "" {
+		return nil
+	}
+	for _, known := range Kinds {
+		if kind == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown transition %q", kind)
+}
+
+// Transition is a change of scene in progress.
+type Transition struct {
+	Kind     string
+	From, To Look
+	Start    int64   // Tick the transition started
+	Duration float64 // Seconds
+	Morph    bool    // Blend the parameters of the two looks rather than their images
+	rate     float64 // Ticks per second
+}
+
+// Begin starts a transition of kind from one look to another at tick,
+// taking duration seconds, or a second when duration isn't positive. It
+// returns nil for a cut.
+func Begin(from, to Look, kind string, duration float64, tick int64, updateRate float64) *Transition {
+	if kind == "" || kind == "cut" {
+		return nil
+	}
+	if duration <= 0 {
+		duration = defaultDuration
+	}
+	return &Transition{Kind: kind, From: from, To: to, Start: tick, Duration: duration, Morph: from.SameRenderers(to), rate: updateRate}
+}
+
+// Progress returns how far through the transition the scene is at tick,
+// eased from 0 to 1.
+func (t *Transition) Progress(tick int64) float64 {
+	p := float64(tick-t.Start) / t.rate / t.Duration
+	return ease(min(1, max(0, p)))
+}
+
+// At returns the look at tick. A morph blends the colour and terrain camera
+// of the two looks; otherwise the images are blended while drawing and the
+// look is the new one.
+func (t *Transition) At(tick int64) Look {
+	if !t.Morph {
+		return t.To
+	}
+	p := t.Progress(tick)
+	l := t.To
+	l.Red = mixInt(t.From.Red, t.To.Red, p)
+	l.Green = mixInt(t.From.Green, t.To.Green, p)
+	l.Blue = mixInt(t.From.Blue, t.To.Blue, p)
+	l.Tilt = t.From.Tilt + (t.To.Tilt-t.From.Tilt)*p
+	l.Scroll = t.From.Scroll + (t.To.Scroll-t.From.Scroll)*p
+	return l
+}
+
+// Done reports whether the transition has finished at tick.
+func (t *Transition) Done(tick int64) bool {
+	return t.Progress(tick) >= 1
+}
+
+func mixInt(a, b int, p float64) int {
+	return a + int(float64(b-a)*p)
+}
+
+// Pending holds a change until the next beat, or until it has waited too
+// long for one.
+type Pending struct {
+	Index int // Change waiting, such as a preset, -1 for none
+	since int64
+	rate  float64
+}
+
+// NewPending creates an empty hold for a show running at updateRate ticks
+// per second.
+func NewPending(updateRate float64) *Pending {
+	return &Pending{Index: -1, rate: updateRate}
+}
+
+// Wait holds the change index from tick, replacing any change waiting.
+func (p *Pending) Wait(index int, tick int64) {
+	p.Index, p.since = index, tick
+}
+
+// Ready returns the change waiting once a beat arrives, onset being set on
+// the tick of one, or after waiting too long for one, and clears it.
+func (p *Pending) Ready(tick int64, onset bool) (int, bool) {
+	if p.Index < 0 || !onset && float64(tick-p.since)/p.rate < beatWait {
+		return -1, false
+	}
+	index := p.Index
+	p.Index = -1
+	return index, true
+}
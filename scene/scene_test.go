@@ -0,0 +1,70 @@
+package scene
+
+import "testing"
+
+const testRate = 60 // Ticks per second
+
+func TestTransitions(t *testing.T) {
+	dark := Look{WaveForm: "smooth", Pattern: "radial", Tilt: 0.1}
+	red := Look{WaveForm: "smooth", Pattern: "radial", Red: 200, Tilt: 0.3}
+	blob := Look{WaveForm: "blob", Pattern: "radial"}
+
+	for _, kind := range []string{"", "cut"} {
+		if tr := Begin(dark, red, kind, 1, 0, testRate); tr != nil {
+			t.Errorf("Transition %q gave %+v, want a cut", kind, tr)
+		}
+	}
+
+	// The same renderers morph their parameters
+	tr := Begin(dark, red, "fade", 1, 60, testRate)
+	if !tr.Morph || tr.At(60) != dark {
+		t.Fatalf("Expected a morph starting from the dark look, got %+v", tr)
+	}
+	half := tr.At(90)
+	if half.Red < 90 || half.Red > 110 || half.Tilt < 0.18 || half.Tilt > 0.22 {
+		t.Errorf("Half way through the morph red is %d and tilt %.2f, want about 100 and 0.2", half.Red, half.Tilt)
+	}
+	if tr.Done(119) || !tr.Done(120) || tr.At(120) != red {
+		t.Errorf("After a second the morph is done %t at %+v, want done at the red look", tr.Done(120), tr.At(120))
+	}
+
+	// Different renderers blend their images, with the new look in place
+	tr = Begin(red, blob, "dissolve", 0, 0, testRate)
+	if tr.Morph || tr.At(0) != blob || tr.Duration != defaultDuration {
+		t.Errorf("Expected a dissolve of %gs to the blob look, got %+v", defaultDuration, tr)
+	}
+	if p := tr.Progress(30); p != 0.5 {
+		t.Errorf("Half way through the dissolve progress is %.2f, want 0.5", p)
+	}
+
+	for _, kind := range Kinds {
+		if err := ValidateKind(kind); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := ValidateKind("spin"); err == nil {
+		t.Error("Expected an error for an unknown transition")
+	}
+}
+
+func TestPending(t *testing.T) {
+	p := NewPending(testRate)
+	if _, ok := p.Ready(0, true); ok {
+		t.Error("A beat with nothing waiting made a change ready")
+	}
+
+	// A change on the beat waits for one
+	p.Wait(3, 0)
+	if _, ok := p.Ready(beatWait/2*testRate, false); ok {
+		t.Error("The change was ready before a beat")
+	}
+	if index, ok := p.Ready(beatWait/2*testRate+1, true); !ok || index != 3 || p.Index != -1 {
+		t.Errorf("On the beat change %d is ready %t with %d waiting, want 3 and none", index, ok, p.Index)
+	}
+
+	// Without a beat it changes anyway
+	p.Wait(1, 100)
+	if index, ok := p.Ready(100+beatWait*testRate, false); !ok || index != 1 {
+		t.Errorf("After the longest wait for a beat change %d is ready %t, want 1", index, ok)
+	}
+}
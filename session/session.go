@@ -0,0 +1,214 @@
+package session
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const version = 1
+
+// Kinds of control event.
+const (
+	KeyEvent     = "key"     // Name is the key, Value 1 when pressed and 0 when released
+	PresetEvent  = "preset"  // Name is the preset applied, Value its index
+	ControlEvent = "control" // Name is an action from the control surface or MIDI, Text and Value its arguments
+)
+
+// Header opens every session file.
+type Header struct {
+	Version    int
+	SampleRate int
+	Seed       int64 // Seed of the visualiser's random source
+	TPS        int   // Visualiser ticks per second, 0 in sessions recorded before it was kept
+	Started    time.Time
+}
+
+// Event is a control change during a session.
+type Event struct {
+	Tick  int64         // Visualiser tick the event was applied on
+	Time  time.Duration // Wall clock time since the session started
+	Kind  string
+	Name  string
+	Text  string // Text argument, only used by control events
+	Value float64
+}
+
+// Step is everything that happened between two visualiser ticks.
+type Step struct {
+	Tick   int64
+	Audio  []float64 // Samples that arrived since the previous tick
+	Events []Event
+}
+
+// record is the unit written to a session file. Exactly one field is set.
+type record struct {
+	Audio []float32
+	Tick  *tickRecord
+	Event *Event
+}
+
+// tickRecord marks a visualiser tick and how many samples had arrived by then.
+type tickRecord struct {
+	Tick     int64
+	Position int64
+}
+
+// Writer records a session to a file. WriteAudio may be called from the
+// audio thread while the other methods are called from the visualiser.
+type Writer struct {
+	mu      sync.Mutex
+	file    *os.File
+	buffer  *bufio.Writer
+	encoder *gob.Encoder
+	started time.Time
+	err     error
+}
+
+// Create starts recording a session to path.
+func Create(path string, header Header) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Version = version
+	if header.Started.IsZero() {
+		header.Started = time.Now()
+	}
+
+	buffer := bufio.NewWriter(file)
+	w := &Writer{
+		file:    file,
+		buffer:  buffer,
+		encoder: gob.NewEncoder(buffer),
+		started: header.Started,
+	}
+	if err := w.encoder.Encode(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteAudio records samples as they arrive from the audio source.
+func (w *Writer) WriteAudio(samples []float64) {
+	audio := make([]float32, len(samples))
+	for i, sample := range samples {
+		audio[i] = float32(sample)
+	}
+	w.write(record{Audio: audio})
+}
+
+// WriteTick records that the visualiser ran tick after position samples had
+// arrived.
+func (w *Writer) WriteTick(tick, position int64) {
+	w.write(record{Tick: &tickRecord{Tick: tick, Position: position}})
+}
+
+// WriteEvent records a control event, stamping it with the time since the
+// session started.
+func (w *Writer) WriteEvent(event Event) {
+	event.Time = time.Since(w.started)
+	w.write(record{Event: &event})
+}
+
+// Close flushes the session to disk and returns the first error met while
+// recording, if any.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.buffer.Flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *Writer) write(r record) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Keep the first error, later writes are pointless once one failed
+	if w.err != nil {
+		return
+	}
+	w.err = w.encoder.Encode(r)
+}
+
+// Reader plays back a recorded session tick by tick.
+type Reader struct {
+	Header   Header
+	file     *os.File
+	decoder  *gob.Decoder
+	pending  []float64
+	position int64
+}
+
+// Open opens the session at path for playback.
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		file:    file,
+		decoder: gob.NewDecoder(bufio.NewReader(file)),
+	}
+	if err := r.decoder.Decode(&r.Header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid session file %s: %v", path, err)
+	}
+	if r.Header.Version != version {
+		file.Close()
+		return nil, fmt.Errorf("session file %s has unsupported version %d", path, r.Header.Version)
+	}
+	return r, nil
+}
+
+// Next returns the next tick of the session, with exactly the audio the
+// visualiser had received by then. It returns io.EOF after the last tick.
+func (r *Reader) Next() (Step, error) {
+	var step Step
+	for {
+		var rec record
+		if err := r.decoder.Decode(&rec); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				// A session cut short by a crash still plays up to the last full record
+				err = io.EOF
+			}
+			return Step{}, err
+		}
+
+		switch {
+		case rec.Audio != nil:
+			for _, sample := range rec.Audio {
+				r.pending = append(r.pending, float64(sample))
+			}
+		case rec.Event != nil:
+			step.Events = append(step.Events, *rec.Event)
+		case rec.Tick != nil:
+			// Audio that arrived after the tick read its samples belongs to the next tick
+			count := int(min(max(rec.Tick.Position-r.position, 0), int64(len(r.pending))))
+			step.Tick = rec.Tick.Tick
+			step.Audio = append([]float64(nil), r.pending[:count]...)
+			r.pending = append(r.pending[:0], r.pending[count:]...)
+			r.position += int64(count)
+			return step, nil
+		}
+	}
+}
+
+// Close closes the session file.
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "set.mzs")
+	w, err := Create(path, Header{SampleRate: 44100, Seed: 42, TPS: 30})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Audio for the second tick arrives before the first tick is written
+	w.WriteAudio([]float64{0.1, 0.2, 0.3})
+	w.WriteEvent(Event{Tick: 0, Kind: KeyEvent, Name: "Space", Value: 1})
+	w.WriteAudio([]float64{0.4, 0.5})
+	w.WriteTick(0, 3)
+	w.WriteEvent(Event{Tick: 1, Kind: PresetEvent, Name: "Drop", Value: 2})
+	w.WriteTick(1, 5)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer r.Close()
+
+	if r.Header.Seed != 42 || r.Header.SampleRate != 44100 || r.Header.TPS != 30 {
+		t.Errorf("Unexpected header %+v", r.Header)
+	}
+
+	first, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(first.Audio) != 3 || float32(first.Audio[2]) != 0.3 {
+		t.Errorf("Expected the first three samples on tick 0, got %v", first.Audio)
+	}
+	if len(first.Events) != 1 || first.Events[0].Name != "Space" {
+		t.Errorf("Expected the space key on tick 0, got %+v", first.Events)
+	}
+
+	second, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if second.Tick != 1 || len(second.Audio) != 2 {
+		t.Errorf("Expected two samples on tick 1, got %d on tick %d", len(second.Audio), second.Tick)
+	}
+	if len(second.Events) != 1 || second.Events[0].Kind != PresetEvent {
+		t.Errorf("Expected the preset change on tick 1, got %+v", second.Events)
+	}
+
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF after the last tick, got %v", err)
+	}
+}
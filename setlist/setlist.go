@@ -0,0 +1,171 @@
+// Package setlist steps through the tracks of a show, firing the preset
+// changes, overlay cues and automation of each as it plays.
+package setlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/idroz/mezmer/timeline"
+)
+
+// Track is one entry of a setlist: the scene to show while it plays and
+// how it changes over time.
+type Track struct {
+	Name       string                    `json:"name"`
+	Duration   float64                   `json:"duration"`   // Seconds before moving on to the next track, 0 to wait to be advanced
+	Preset     string                    `json:"preset"`     // Preset applied when the track starts
+	Overlays   []string                  `json:"overlays"`   // Overlays shown when the track starts
+	Cues       []Cue                     `json:"cues"`       // Changes at points within the track
+	Automation map[string]timeline.Track `json:"automation"` // Curves for red, green, blue, tilt and scroll from the start of the track
+}
+
+// Cue is a change at a point within a track.
+type Cue struct {
+	Time    float64 `json:"time"`    // Seconds from the start of the track
+	Preset  string  `json:"preset"`  // Preset to switch to, optional
+	Overlay string  `json:"overlay"` // Overlay to cue on or off, optional
+}
+
+// Show is what a setlist changes as it plays.
+type Show interface {
+	ApplyPreset(name string)
+	ShowOverlay(name string) // Show an overlay, leaving it up if it is showing
+	CueOverlay(name string)  // Show an overlay, or fade it out if it is showing
+	Automate(parameter string, value float64)
+}
+
+// Setlist steps through the tracks of a show.
+type Setlist struct {
+	Tracks    []Track
+	rate      float64 // Ticks per second
+	current   int     // Index of the playing track, -1 before the show starts
+	startedAt int64   // Tick the current track started
+	nextCue   int     // Index of the next cue to fire in the current track
+}
+
+// Load reads a JSON array of tracks from path, checking that the presets
+// and overlays they name exist. The setlist is played at updateRate ticks
+// per second.
+func Load(path string, updateRate float64, presets, overlays []string) (*Setlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks []Track
+	if err := json.Unmarshal(data, &tracks); err != nil {
+		return nil, fmt.Errorf("invalid setlist %s: %v", path, err)
+	}
+	for i, t := range tracks {
+		if t.Name == "" {
+			tracks[i].Name = fmt.Sprintf("Track %d", i+1)
+		}
+		if err := validate(t, presets, overlays); err != nil {
+			return nil, fmt.Errorf("invalid track %s in %s: %v", tracks[i].Name, path, err)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("setlist %s has no tracks", path)
+	}
+	return &Setlist{Tracks: tracks, rate: updateRate, current: -1}, nil
+}
+
+func validate(t Track, presets, overlays []string) error {
+	if t.Preset != "" && !slices.Contains(presets, t.Preset) {
+		return fmt.Errorf("unknown preset %q", t.Preset)
+	}
+	for _, name := range t.Overlays {
+		if !slices.Contains(overlays, name) {
+			return fmt.Errorf("unknown overlay %q", name)
+		}
+	}
+	for i, c := range t.Cues {
+		if i > 0 && c.Time < t.Cues[i-1].Time {
+			return fmt.Errorf("cue at %gs comes after one at %gs", c.Time, t.Cues[i-1].Time)
+		}
+		if c.Preset != "" && !slices.Contains(presets, c.Preset) {
+			return fmt.Errorf("unknown preset %q", c.Preset)
+		}
+		if c.Overlay != "" && !slices.Contains(overlays, c.Overlay) {
+			return fmt.Errorf("unknown overlay %q", c.Overlay)
+		}
+	}
+	for name, curve := range t.Automation {
+		switch name {
+		case "red", "green", "blue", "tilt", "scroll":
+		default:
+			return fmt.Errorf("cannot automate %q", name)
+		}
+		if err := curve.Validate(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Current returns the index of the playing track, or -1 before the show
+// starts.
+func (s *Setlist) Current() int {
+	return s.current
+}
+
+// Elapsed returns the seconds the current track has played at tick.
+func (s *Setlist) Elapsed(tick int64) float64 {
+	return float64(tick-s.startedAt) / s.rate
+}
+
+// Start switches the show to the track at index at tick. Indexes outside
+// the setlist are ignored.
+func (s *Setlist) Start(index int, tick int64, show Show) {
+	if index < 0 || index >= len(s.Tracks) {
+		return
+	}
+	s.current, s.startedAt, s.nextCue = index, tick, 0
+
+	t := s.Tracks[index]
+	if t.Preset != "" {
+		show.ApplyPreset(t.Preset)
+	}
+	for _, name := range t.Overlays {
+		show.ShowOverlay(name)
+	}
+}
+
+// Step moves the show step tracks on, or back when step is negative.
+// Stepping past the first or last track does nothing, so the track playing
+// isn't restarted.
+func (s *Setlist) Step(step int, tick int64, show Show) {
+	s.Start(s.current+step, tick, show)
+}
+
+// Update starts the show, moves on to the next track when one runs out,
+// and fires the cues and automation of the current track at tick.
+func (s *Setlist) Update(tick int64, show Show) {
+	if s.current < 0 {
+		s.Start(0, tick, show)
+	}
+
+	t := s.Tracks[s.current]
+	elapsed := s.Elapsed(tick)
+	if t.Duration > 0 && elapsed >= t.Duration && s.current < len(s.Tracks)-1 {
+		s.Start(s.current+1, tick, show)
+		t, elapsed = s.Tracks[s.current], 0
+	}
+
+	for ; s.nextCue < len(t.Cues) && elapsed >= t.Cues[s.nextCue].Time; s.nextCue++ {
+		c := t.Cues[s.nextCue]
+		if c.Preset != "" {
+			show.ApplyPreset(c.Preset)
+		}
+		if c.Overlay != "" {
+			show.CueOverlay(c.Overlay)
+		}
+	}
+
+	for name, curve := range t.Automation {
+		show.Automate(name, curve.At(elapsed))
+	}
+}
@@ -0,0 +1,130 @@
+package setlist
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/idroz/mezmer/timeline"
+)
+
+const testRate = 60 // Ticks per second
+
+// scene is a Show that keeps what the setlist asked for.
+type scene struct {
+	preset   string
+	overlays []string // Every overlay shown or cued, in order
+	red      float64
+}
+
+func (s *scene) ApplyPreset(name string) { s.preset = name }
+func (s *scene) ShowOverlay(name string) { s.overlays = append(s.overlays, "show "+name) }
+func (s *scene) CueOverlay(name string)  { s.overlays = append(s.overlays, "cue "+name) }
+func (s *scene) Automate(p string, v float64) {
+	if p == "red" {
+		s.red = v
+	}
+}
+
+func TestSetlist(t *testing.T) {
+	presets, overlays := []string{"Calm", "Drop"}, []string{"Title"}
+	tracks := []Track{
+		{Name: "Intro", Preset: "Calm", Duration: 2, Overlays: []string{"Title"}, Cues: []Cue{{Time: 1, Preset: "Drop", Overlay: "Title"}},
+			Automation: map[string]timeline.Track{"red": {{Time: 0, Value: 0}, {Time: 2, Value: 200}}}},
+		{Name: "Outro", Preset: "Calm"},
+	}
+	for _, tr := range tracks {
+		if err := validate(tr, presets, overlays); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &Setlist{Tracks: tracks, rate: testRate, current: -1}
+
+	show := &scene{}
+	var tick int64
+	run := func(seconds float64) {
+		for i := 0; i < int(seconds*testRate); i++ {
+			s.Update(tick, show)
+			tick++
+		}
+	}
+
+	run(0.5)
+	if show.preset != "Calm" || show.red < 40 || show.red > 60 {
+		t.Errorf("Half a second in: preset %q and red %.0f, want Calm and about 50", show.preset, show.red)
+	}
+	run(1)
+	if show.preset != "Drop" || !slices.Equal(show.overlays, []string{"show Title", "cue Title"}) {
+		t.Errorf("After the cue: preset %q and overlays %q, want Drop with the title shown then cued", show.preset, show.overlays)
+	}
+	run(1)
+	if s.Current() != 1 || show.preset != "Calm" {
+		t.Errorf("After the intro: track %d with preset %q, want the outro with Calm", s.Current(), show.preset)
+	}
+	run(10)
+	if s.Current() != 1 {
+		t.Errorf("The last track moved on to %d", s.Current())
+	}
+	if elapsed := s.Elapsed(tick); elapsed != 10.5 {
+		t.Errorf("The outro has played %.2fs, want 10.5", elapsed)
+	}
+
+	// Stepping past either end leaves the track playing
+	startedAt := s.startedAt
+	s.Step(1, tick, show)
+	if s.Current() != 1 || s.startedAt != startedAt {
+		t.Errorf("Stepping past the last track moved to track %d started at tick %d", s.Current(), s.startedAt)
+	}
+	s.Step(-1, tick, show)
+	run(1)
+	startedAt = s.startedAt
+	s.Step(-1, tick, show)
+	if s.Current() != 0 || s.startedAt != startedAt {
+		t.Errorf("Stepping before the first track moved to track %d started at tick %d", s.Current(), s.startedAt)
+	}
+	s.Start(5, tick, show)
+	if s.Current() != 0 {
+		t.Errorf("Starting a track past the end moved to track %d", s.Current())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	presets, overlays := []string{"Calm"}, []string{"Title"}
+	for _, bad := range []Track{
+		{Preset: "Missing"},
+		{Overlays: []string{"Missing"}},
+		{Cues: []Cue{{Preset: "Missing"}}},
+		{Cues: []Cue{{Overlay: "Missing"}}},
+		{Cues: []Cue{{Time: 2}, {Time: 1}}},
+		{Automation: map[string]timeline.Track{"speed": nil}},
+		{Automation: map[string]timeline.Track{"red": {{Time: 1}, {Time: 0}}}},
+	} {
+		if err := validate(bad, presets, overlays); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setlist.json")
+	load := func(data string) (*Setlist, error) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return Load(path, testRate, []string{"Calm"}, nil)
+	}
+
+	s, err := load(`[{"preset": "Calm"}, {"name": "Encore"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Current() != -1 || s.Tracks[0].Name != "Track 1" || s.Tracks[1].Name != "Encore" {
+		t.Errorf("Loaded track %d of %+v, want none yet with Track 1 and Encore", s.Current(), s.Tracks)
+	}
+	for _, bad := range []string{`[]`, `{}`, `[{"preset": "Drop"}]`} {
+		if _, err := load(bad); err == nil {
+			t.Errorf("Expected an error loading %s", bad)
+		}
+	}
+}
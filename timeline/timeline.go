@@ -0,0 +1,113 @@
+// Package timeline animates values over time with keyframes joined by
+// easing curves.
+package timeline
+
+import (
+	"fmt"
+	"math"
+)
+
+// Ease maps progress between two keyframes, from 0 to 1, onto the share of
+// the way between their values.
+type Ease func(t float64) float64
+
+var eases = map[string]Ease{
+	"":       linear,
+	"linear": linear,
+	"in":     func(t float64) float64 { return t * t * t },
+	"out":    func(t float64) float64 { return 1 - math.Pow(1-t, 3) },
+	"in-out": func(t float64) float64 { return t * t * (3 - 2*t) },
+	"step":   func(t float64) float64 { return math.Floor(t) },
+}
+
+func linear(t float64) float64 { return t }
+
+// ParseEase returns the easing curve called name: linear (the default), in,
+// out, in-out or step.
+func ParseEase(name string) (Ease, error) {
+	ease, ok := eases[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown easing %q", name)
+	}
+	return ease, nil
+}
+
+// Keyframe is a value at a time in seconds.
+type Keyframe struct {
+	Time  float64 `json:"time"`
+	Value float64 `json:"value"`
+	Ease  string  `json:"ease"` // Curve from the previous keyframe to this one
+}
+
+// Track is a sequence of keyframes in time order.
+type Track []Keyframe
+
+// Validate checks that the keyframes are in order and their easings exist.
+func (t Track) Validate() error {
+	for i, key := range t {
+		if _, err := ParseEase(key.Ease); err != nil {
+			return err
+		}
+		if i > 0 && key.Time < t[i-1].Time {
+			return fmt.Errorf("keyframe at %gs comes after one at %gs", key.Time, t[i-1].Time)
+		}
+	}
+	return nil
+}
+
+// At returns the value of the track at time, holding the first value before
+// the track starts and the last after it ends.
+func (t Track) At(time float64) float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	if time <= t[0].Time {
+		return t[0].Value
+	}
+	for i := 1; i < len(t); i++ {
+		next := t[i]
+		if time >= next.Time {
+			continue
+		}
+		prev := t[i-1]
+		ease := eases[next.Ease]
+		if ease == nil {
+			ease = linear
+		}
+		progress := ease((time - prev.Time) / (next.Time - prev.Time))
+		return prev.Value + (next.Value-prev.Value)*progress
+	}
+	return t[len(t)-1].Value
+}
+
+// End returns the time of the last keyframe.
+func (t Track) End() float64 {
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].Time
+}
+
+// Envelope returns how visible something cued time seconds ago is, fading in
+// over fade seconds and out again from until. It returns false once it has
+// faded out. An until of zero or less holds it indefinitely.
+func Envelope(time, fade, until float64) (float64, bool) {
+	if time < 0 {
+		return 0, true
+	}
+	level := 1.0
+	if fade > 0 {
+		level = math.Min(1, time/fade)
+	}
+	if until > 0 && time >= until {
+		if fade <= 0 {
+			return 0, false
+		}
+		// Fade out from wherever the fade in had reached
+		level = math.Min(1, until/fade) - (time-until)/fade
+		if level <= 0 {
+			return 0, false
+		}
+	}
+	return level, true
+}
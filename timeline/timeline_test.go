@@ -0,0 +1,89 @@
+package timeline
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrack(t *testing.T) {
+	track := Track{
+		{Time: 1, Value: 0},
+		{Time: 3, Value: 10},
+		{Time: 4, Value: 20, Ease: "step"},
+		{Time: 6, Value: 0, Ease: "in-out"},
+	}
+	if err := track.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		time, want float64
+	}{
+		{0, 0},     // Held before the start
+		{2, 5},     // Halfway along a linear segment
+		{3.5, 10},  // A step holds until the next keyframe
+		{4, 20},    // and lands on it
+		{5, 10},    // Smoothstep is symmetric about its middle
+		{100, 0},   // Held after the end
+		{6, 0},     // On the last keyframe
+		{1, 0},     // On the first keyframe
+		{2.5, 7.5}, // Three quarters along a linear segment
+	} {
+		if got := track.At(tc.time); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("At(%g) = %g, want %g", tc.time, got, tc.want)
+		}
+	}
+	if end := track.End(); end != 6 {
+		t.Errorf("End() = %g, want 6", end)
+	}
+}
+
+func TestEases(t *testing.T) {
+	for _, name := range []string{"linear", "in", "out", "in-out"} {
+		ease, err := ParseEase(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ease(0) != 0 || ease(1) != 1 {
+			t.Errorf("%s runs from %g to %g, want 0 to 1", name, ease(0), ease(1))
+		}
+	}
+	in, _ := ParseEase("in")
+	out, _ := ParseEase("out")
+	if in(0.5) >= 0.5 || out(0.5) <= 0.5 {
+		t.Errorf("in(0.5) = %g and out(0.5) = %g, want slow then fast and fast then slow", in(0.5), out(0.5))
+	}
+	if _, err := ParseEase("bounce"); err == nil {
+		t.Error("Expected an error for an unknown easing")
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	if err := (Track{{Time: 2}, {Time: 1}}).Validate(); err == nil {
+		t.Error("Expected an error for keyframes out of order")
+	}
+	if err := (Track{{Time: 1, Ease: "wobble"}}).Validate(); err == nil {
+		t.Error("Expected an error for an unknown easing")
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	for _, tc := range []struct {
+		time, fade, until float64
+		want              float64
+		wantOn            bool
+	}{
+		{0.5, 1, 0, 0.5, true},     // Fading in
+		{30, 1, 0, 1, true},        // Held indefinitely
+		{5, 1, 4, 0, false},        // Faded out after until
+		{4.25, 1, 4, 0.75, true},   // Fading out
+		{0.75, 1, 0.5, 0.25, true}, // Hidden halfway through fading in
+		{2, 0, 2, 0, false},        // Cut with no fade
+		{1, 0, 0, 1, true},         // Shown with no fade
+	} {
+		got, on := Envelope(tc.time, tc.fade, tc.until)
+		if on != tc.wantOn || math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("Envelope(%g, %g, %g) = %g, %t, want %g, %t", tc.time, tc.fade, tc.until, got, on, tc.want, tc.wantOn)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"image/color"
 	"math"
 
 	"github.com/mjibson/go-dsp/fft"
@@ -45,3 +46,35 @@ func CalculateDominantFrequency(samples []float64, sampleRate int) float64 {
 
 	return highestFrequency
 }
+
+// HSVToRGB converts a hue in degrees and saturation and value between 0 and 1
+// into an opaque RGB colour.
+func HSVToRGB(hue, saturation, value float64) color.RGBA {
+	hue = math.Mod(math.Mod(hue, 360)+360, 360)
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	m := value - chroma
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = chroma, x, 0
+	case hue < 120:
+		r, g, b = x, chroma, 0
+	case hue < 180:
+		r, g, b = 0, chroma, x
+	case hue < 240:
+		r, g, b = 0, x, chroma
+	case hue < 300:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 255,
+	}
+}
@@ -0,0 +1,91 @@
+package visualiser
+
+import (
+	"github.com/idroz/mezmer/autopilot"
+	"github.com/idroz/mezmer/utils"
+)
+
+const (
+	paletteTime = 2.0 // Seconds a palette change blends over
+	sceneTime   = 1.5 // Seconds a section change dissolves over
+)
+
+// updatePilot passes the latest frame to the pilot and makes the change it
+// asks for.
+func (v *audioVisualizer) updatePilot() {
+	v.pilotFeatures = append(v.pilotFeatures[:0],
+		v.frame.Normalized.Centroid,
+		v.frame.Normalized.Spread,
+		v.frame.Normalized.Flatness,
+		v.frame.Normalized.Rolloff,
+		v.frame.Normalized.ZeroCrossingRate,
+	)
+	for _, level := range v.frame.Bands {
+		v.pilotFeatures = append(v.pilotFeatures, min(1, level))
+	}
+
+	busy := v.transition != nil || v.pending.Index >= 0
+	switch change := v.pilot.Update(v.tick, v.pilotFeatures, v.frame.RMS, busy); change {
+	case autopilot.NewScene, autopilot.DropScene:
+		v.pilotScene(change)
+	case autopilot.NewPalette:
+		v.pilotPalette(false)
+	case autopilot.DarkPalette:
+		v.pilotPalette(true)
+	}
+}
+
+// pilotScene moves on to another preset, or without presets to another
+// waveform and pattern. A drop doesn't wait for a beat or blend in.
+func (v *audioVisualizer) pilotScene(change autopilot.Change) {
+	if index, ok := v.pilot.Pick(v.currentPreset, len(v.presets), v.rng); ok {
+		if change == autopilot.DropScene {
+			v.switchPreset(index, change.Transition(v.presets[index].Transition))
+		} else {
+			v.applyPreset(index)
+		}
+		return
+	}
+
+	from := v.currentLook()
+	v.waveForm = v.pickOther(waveFormNames[1:], v.waveForm)
+	v.fillWaveform = false
+	v.pointType = v.pickOther(patternNames, v.pointType)
+	v.colorScheme = v.pilotColor(false)
+	v.beginTransition(from, change.Transition("dissolve"), sceneTime)
+	v.pilot.SceneChanged(v.tick)
+	v.pilot.PaletteChanged(v.tick)
+}
+
+// pilotPalette blends to a new colour scheme, darker for a breakdown. The
+// harmonic colours are left alone.
+func (v *audioVisualizer) pilotPalette(dark bool) {
+	if v.harmonicColor {
+		return
+	}
+	from := v.currentLook()
+	v.colorScheme = v.pilotColor(dark)
+	v.beginTransition(from, "fade", paletteTime)
+	v.pilot.PaletteChanged(v.tick)
+}
+
+// pilotColor picks a random saturated colour.
+func (v *audioVisualizer) pilotColor(dark bool) colorSceme {
+	value := 1.0
+	if dark {
+		value = 0.5
+	}
+	clr := utils.HSVToRGB(v.rng.Float64()*360, 1, value)
+	return colorSceme{red: int(clr.R), green: int(clr.G), blue: int(clr.B)}
+}
+
+// pickOther picks a random name other than current.
+func (v *audioVisualizer) pickOther(names []string, current string) string {
+	var others []string
+	for _, name := range names {
+		if name != current {
+			others = append(others, name)
+		}
+	}
+	return others[v.rng.Intn(len(others))]
+}
@@ -0,0 +1,68 @@
+package visualiser
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/waveforms"
+)
+
+const (
+	blobSize      = 0.45  // Radius of the outer layer at full level, as a fraction of the shorter screen side
+	blobAmplitude = 0.6   // How far the audio pushes the edge in and out
+	blobSpin      = 0.001 // Turns per tick
+	blobRimWidth  = 0.08
+)
+
+// blobLayer is one of the concentric shapes of the blob waveform.
+type blobLayer struct {
+	band  string  // Band whose level sizes the layer
+	scale float64 // Size relative to the outer layer
+}
+
+var blobLayers = []blobLayer{
+	{band: "bass", scale: 1},
+	{band: "lowmid", scale: 0.7},
+	{band: "presence", scale: 0.45},
+}
+
+// drawBlob draws the blob waveform: solid layers from the outside in, each
+// lit from the centre, shaded towards its edge and rimmed with light.
+func (v *audioVisualizer) drawBlob(screen *ebiten.Image) {
+	base := color.RGBA{R: uint8(v.colorScheme.red), G: uint8(v.colorScheme.green), B: uint8(v.colorScheme.blue), A: 0xff}
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+	maxRadius := math.Min(float64(v.screenWidth), float64(v.screenHeight)) * blobSize
+
+	v.meshVertices, v.pathIndices = v.meshVertices[:0], v.pathIndices[:0]
+	for i, layer := range blobLayers {
+		level := v.bandLevel(layer.band)
+		light := float64(i) / float64(len(blobLayers)) // Inner layers are lighter
+
+		inner := mixColor(base, white, 0.3+0.4*light)
+		outer := mixColor(base, black, 0.5-0.3*light)
+		inner.A, outer.A = 0xe6, 0xe6
+		rim := white
+		rim.A = uint8(90 + 120*level)
+		style := waveforms.BlobStyle{Inner: inner, Outer: outer, Rim: rim, RimWidth: blobRimWidth}
+
+		// Alternate layers turn in opposite directions
+		spin := float64(v.tick) * blobSpin
+		if i%2 == 1 {
+			spin = -spin
+		}
+		radius := maxRadius * layer.scale * (0.5 + 0.5*level)
+		v.meshVertices, v.pathIndices = waveforms.BlobWaveform(v.meshVertices, v.pathIndices, v.samples,
+			float64(v.screenWidth)/2, float64(v.screenHeight)/2, radius, blobAmplitude, spin, style)
+	}
+	v.pathVertices = drawMesh(screen, v.meshVertices, v.pathIndices, v.pathVertices)
+}
+
+// mixColor blends a towards b by t between 0 and 1.
+func mixColor(a, b color.RGBA, t float64) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t)
+	}
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
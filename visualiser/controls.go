@@ -0,0 +1,212 @@
+package visualiser
+
+import (
+	"image"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/control"
+	"github.com/idroz/mezmer/session"
+)
+
+const (
+	stateInterval     = 6  // Ticks between state updates sent to the control surface
+	thumbnailInterval = 15 // Ticks between previews of the output
+	thumbnailWidth    = 480
+	thumbnailHeight   = 270
+)
+
+var (
+	waveFormNames = []string{"", "smooth", "ferroliquid", "bezier", "blob", "ridges", "terrain"}
+	patternNames  = []string{"radial", "spiral", "slinky", "spikes", "flock"}
+)
+
+// controlState is what the control surface shows of the visualiser.
+type controlState struct {
+	Device    string         `json:"device"`
+	FPS       float64        `json:"fps"`
+	Recording bool           `json:"recording"`
+	Volume    float64        `json:"volume"`
+	Pitch     string         `json:"pitch"`
+	Key       string         `json:"key"`
+	Chord     string         `json:"chord"`
+	Bands     []bandState    `json:"bands"`
+	Presets   []string       `json:"presets"`
+	Preset    int            `json:"preset"`
+	WaveForms []string       `json:"waveforms"`
+	WaveForm  string         `json:"waveform"`
+	Fill      bool           `json:"fill"`
+	Patterns  []string       `json:"patterns"`
+	Pattern   string         `json:"pattern"`
+	Fluid     bool           `json:"fluid"`
+	ForceSets []string       `json:"forceSets"`
+	ForceSet  string         `json:"forceSet"`
+	Color     map[string]int `json:"color"`
+	Harmonic  bool           `json:"harmonic"`
+	Fifths    bool           `json:"fifths"`
+	Overlays  []overlayState `json:"overlays"`
+	Tracks    []string       `json:"tracks"`
+	Track     int            `json:"track"`
+	TrackTime float64        `json:"trackTime"` // Seconds into the current track
+	Autopilot bool           `json:"autopilot"`
+}
+
+type overlayState struct {
+	Name  string `json:"name"`
+	Shown bool   `json:"shown"`
+}
+
+type bandState struct {
+	Name  string  `json:"name"`
+	Level float64 `json:"level"`
+}
+
+// updateControls applies the actions requested from the control surface and
+// publishes the visualiser's state back to it.
+func (v *audioVisualizer) updateControls() {
+	v.actions = v.control.Pending(v.actions[:0])
+	for _, action := range v.actions {
+		v.perform(action)
+	}
+
+	if v.tick%stateInterval == 0 {
+		if err := v.control.SetState(v.controlState()); err != nil {
+			log.Printf("Failed to publish state: %v", err)
+		}
+	}
+}
+
+// perform records and applies an action from outside the visualiser.
+func (v *audioVisualizer) perform(action control.Action) {
+	// Replays take their actions from the session instead
+	if v.replay != nil {
+		return
+	}
+	if v.recorder != nil {
+		v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.ControlEvent, Name: action.Name, Text: action.Text, Value: action.Value})
+	}
+	v.applyControl(action)
+}
+
+// applyControl makes the change asked for by action.
+func (v *audioVisualizer) applyControl(action control.Action) {
+	switch action.Name {
+	case "preset":
+		if index := int(action.Value); index >= 0 && index < len(v.presets) {
+			v.applyPreset(index)
+		}
+	case "waveform":
+		v.changeLook(func() { v.waveForm = action.Text })
+	case "fill":
+		v.changeLook(func() { v.fillWaveform = action.Value > 0 })
+	case "pattern":
+		v.changeLook(func() { v.pointType = action.Text })
+	case "fluid":
+		v.fluidOn = action.Value > 0
+	case "forces":
+		for i, set := range forceSets {
+			if set.Name == action.Text {
+				v.applyForceSet(i)
+			}
+		}
+	case "color":
+		value := min(255, max(0, int(action.Value)))
+		switch action.Text {
+		case "red":
+			v.colorScheme.red = value
+		case "green":
+			v.colorScheme.green = value
+		case "blue":
+			v.colorScheme.blue = value
+		}
+	case "harmonic":
+		v.harmonicColor = action.Value > 0
+	case "fifths":
+		v.fifthsOrder = action.Value > 0
+	case "overlay":
+		v.cueOverlay(action.Text)
+	case "autopilot":
+		v.pilot.On = action.Value > 0
+	case "track":
+		if v.setlist != nil {
+			v.setlist.Start(int(action.Value), v.tick, show{v})
+		}
+	default:
+		log.Printf("Unknown control action %q", action.Name)
+	}
+}
+
+func (v *audioVisualizer) controlState() controlState {
+	state := controlState{
+		Device:    v.deviceLabel(),
+		FPS:       ebiten.ActualFPS(),
+		Recording: v.recorder != nil,
+		Volume:    v.volume,
+		Key:       v.frame.Key.String(),
+		Chord:     v.frame.Chord.String(),
+		Presets:   make([]string, 0, len(v.presets)),
+		Preset:    v.currentPreset,
+		WaveForms: waveFormNames,
+		WaveForm:  v.waveForm,
+		Fill:      v.fillWaveform,
+		Patterns:  patternNames,
+		Pattern:   v.pointType,
+		Fluid:     v.fluidOn,
+		ForceSets: make([]string, 0, len(forceSets)),
+		Color:     map[string]int{"red": v.colorScheme.red, "green": v.colorScheme.green, "blue": v.colorScheme.blue},
+		Harmonic:  v.harmonicColor,
+		Fifths:    v.fifthsOrder,
+		Overlays:  make([]overlayState, 0, len(v.overlays)),
+		Tracks:    make([]string, 0),
+		Track:     -1,
+		Autopilot: v.pilot.On,
+	}
+	if v.frame.Pitch.Voiced() {
+		state.Pitch = v.frame.Pitch.Note.String()
+	}
+	for _, band := range v.analyzer.Bands() {
+		state.Bands = append(state.Bands, bandState{Name: band.Name, Level: v.bandLevel(band.Name)})
+	}
+	for _, p := range v.presets {
+		state.Presets = append(state.Presets, p.Name)
+	}
+	for _, set := range forceSets {
+		state.ForceSets = append(state.ForceSets, set.Name)
+	}
+	for _, layer := range v.overlays {
+		state.Overlays = append(state.Overlays, overlayState{Name: layer.Name, Shown: layer.Showing()})
+	}
+	if v.setlist != nil {
+		for _, t := range v.setlist.Tracks {
+			state.Tracks = append(state.Tracks, t.Name)
+		}
+		state.Track = v.setlist.Current()
+		state.TrackTime = v.setlist.Elapsed(v.tick)
+	}
+	if v.forceSet >= 0 {
+		state.ForceSet = forceSets[v.forceSet].Name
+	}
+	return state
+}
+
+// publishThumbnail sends a scaled-down copy of the output to the control
+// surface every few ticks.
+func (v *audioVisualizer) publishThumbnail(screen *ebiten.Image) {
+	// Draw can run more often than Update, so only once per tick
+	if v.tick%thumbnailInterval != 0 || v.tick == v.thumbnailTick {
+		return
+	}
+	v.thumbnailTick = v.tick
+	if v.thumbnail == nil {
+		v.thumbnail = ebiten.NewImage(thumbnailWidth, thumbnailHeight)
+		v.thumbnailPixels = image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+	}
+
+	bounds := screen.Bounds()
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(thumbnailWidth)/float64(bounds.Dx()), float64(thumbnailHeight)/float64(bounds.Dy()))
+	v.thumbnail.Clear()
+	v.thumbnail.DrawImage(screen, op)
+	v.thumbnail.ReadPixels(v.thumbnailPixels.Pix)
+	v.control.SetThumbnail(v.thumbnailPixels)
+}
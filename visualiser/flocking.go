@@ -0,0 +1,110 @@
+package visualiser
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/flock"
+)
+
+const (
+	flockSize       = 3000 // Boids in the flock pattern, well within one batch of triangles
+	scatterLevel    = 0.7  // Bass level whose rise scatters the flock
+	scatterStrength = 8    // Push given by a scatter at full bass, in pixels per tick
+	sustainRise     = 0.02 // Share of the way to formation gained each tick a note is held
+	sustainFall     = 0.9  // Share of the formation kept each tick without a held note
+	boidLength      = 7    // Length of a boid's triangle in pixels
+	boidWidth       = 4
+)
+
+// stepFlock moves the flock pattern on by a tick. Bass scatters the flock
+// and a held note gradually pulls it into a ring around the centre.
+func (v *audioVisualizer) stepFlock() {
+	if v.pointType != "flock" && (v.transition == nil || v.transition.From.Pattern != "flock") {
+		v.lastBass = v.bandLevel("bass")
+		return
+	}
+	width, height := float64(v.screenWidth), float64(v.screenHeight)
+	if v.flock == nil {
+		v.flock = flock.New(flockSize, width, height, v.rng)
+	}
+	v.flock.Resize(width, height)
+
+	// A note counts as held while its pitch stays on the same note
+	pitch := v.frame.Pitch
+	if pitch.Voiced() && pitch.Note.MIDI == v.heldNote {
+		v.sustain += (1 - v.sustain) * sustainRise * pitch.Confidence
+	} else {
+		v.sustain *= sustainFall
+	}
+	v.heldNote = pitch.Note.MIDI
+
+	bass := v.bandLevel("bass")
+	params := flock.DefaultParams
+	params.Separation *= 1 + 4*bass
+	params.Alignment *= 1 + 2*v.sustain
+	params.Cohesion *= 1 + 3*v.sustain
+	params.Formation = v.sustain
+	params.FormationRadius = 0.3 * math.Min(width, height)
+	params.MaxSpeed *= 1 + v.volume/2
+	v.flock.Params = params
+
+	if bass > scatterLevel && v.lastBass <= scatterLevel {
+		v.flock.Scatter(width/2, height/2, scatterStrength*bass, math.Max(width, height)/2)
+	}
+	v.lastBass = bass
+
+	// Forces and the fluid act on the boids as on any other points
+	for i := range v.flock.Boids {
+		b := &v.flock.Boids[i]
+		if len(v.forceField) > 0 {
+			ax, ay := v.forceField.Accel(b.X, b.Y, float64(v.tick))
+			b.VX += ax
+			b.VY += ay
+		}
+		if v.fluidOn {
+			dx, dy := v.flow(b.X, b.Y)
+			b.VX += (dx - b.VX) * (1 - particleDrag)
+			b.VY += (dy - b.VY) * (1 - particleDrag)
+		}
+	}
+	v.flock.Step()
+}
+
+// drawFlock draws each boid as a small triangle pointing along its heading,
+// brighter the faster it flies.
+func (v *audioVisualizer) drawFlock(screen *ebiten.Image) {
+	if v.pointType != "flock" || v.flock == nil {
+		return
+	}
+
+	vertices, indices := v.pathVertices[:0], v.pathIndices[:0]
+	maxSpeed := v.flock.Params.MaxSpeed
+	for _, b := range v.flock.Boids {
+		speed := math.Hypot(b.VX, b.VY)
+		if speed == 0 {
+			continue
+		}
+		dx, dy := b.VX/speed, b.VY/speed
+		brightness := float32(0.4 + 0.6*math.Min(1, speed/maxSpeed))
+		r := float32(v.colorScheme.red) / 255 * brightness
+		g := float32(v.colorScheme.green) / 255 * brightness
+		bl := float32(v.colorScheme.blue) / 255 * brightness
+
+		base := uint16(len(vertices))
+		for _, corner := range [3][2]float64{
+			{b.X + dx*boidLength/2, b.Y + dy*boidLength/2},
+			{b.X - dx*boidLength/2 - dy*boidWidth/2, b.Y - dy*boidLength/2 + dx*boidWidth/2},
+			{b.X - dx*boidLength/2 + dy*boidWidth/2, b.Y - dy*boidLength/2 - dx*boidWidth/2},
+		} {
+			vertices = append(vertices, ebiten.Vertex{
+				DstX: float32(corner[0]), DstY: float32(corner[1]),
+				SrcX: 1, SrcY: 1,
+				ColorR: r, ColorG: g, ColorB: bl, ColorA: brightness,
+			})
+		}
+		indices = append(indices, base, base+1, base+2)
+	}
+	screen.DrawTriangles(vertices, indices, whiteSubImage, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+	v.pathVertices, v.pathIndices = vertices, indices
+}
@@ -0,0 +1,84 @@
+package visualiser
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/fluid"
+)
+
+const (
+	fluidWidth       = 96 // Simulation cells across the screen
+	fluidHeight      = 54
+	fluidViscosity   = 0.0001
+	fluidDiffusion   = 0.0005
+	fluidDissipation = 0.4  // Share of the dye fading each second
+	onsetThreshold   = 0.6  // Normalized spectral flux that counts as an onset
+	onsetSpeed       = 60   // Cells per second given to the fluid by an onset at full volume
+	onsetRadius      = 6    // Cells stirred by an onset
+	bassDye          = 0.4  // Dye per second fed into the centre at full bass
+	particleDrag     = 0.97 // Share of a particle's own velocity kept each tick in the flow
+)
+
+// stepFluid stirs the fluid on onsets, feeds it dye with the bass and moves
+// it on by a tick.
+func (v *audioVisualizer) stepFluid() {
+	if !v.fluidOn && (v.transition == nil || !v.transition.From.Fluid) {
+		return
+	}
+
+	if v.onset {
+		// Kick the fluid in a random direction from near the centre
+		angle := v.rng.Float64() * 2 * math.Pi
+		x := fluidWidth/2 + (v.rng.Float64()-0.5)*fluidWidth/4
+		y := fluidHeight/2 + (v.rng.Float64()-0.5)*fluidHeight/4
+		speed := onsetSpeed * math.Min(1, 0.3+v.volume)
+		v.fluid.AddVelocity(x, y, math.Cos(angle)*speed, math.Sin(angle)*speed, onsetRadius)
+		v.fluid.AddDye(x, y, 1, onsetRadius)
+	}
+
+	v.fluid.AddDye(fluidWidth/2, fluidHeight/2, bassDye*v.bandLevel("bass")/v.tps, onsetRadius/2)
+	v.fluid.Step(1 / v.tps)
+}
+
+// flow returns how far the fluid carries something at screen position
+// (x, y) in one tick, in pixels.
+func (v *audioVisualizer) flow(x, y float64) (float64, float64) {
+	scaleX := float64(v.screenWidth) / fluidWidth
+	scaleY := float64(v.screenHeight) / fluidHeight
+	vx, vy := v.fluid.Velocity(x/scaleX, y/scaleY)
+	return vx * scaleX / v.tps, vy * scaleY / v.tps
+}
+
+// drawFluid draws the dye in the colour scheme, stretched over the screen.
+func (v *audioVisualizer) drawFluid(screen *ebiten.Image) {
+	if v.fluidImage == nil {
+		v.fluidImage = ebiten.NewImage(fluidWidth, fluidHeight)
+		v.fluidPixels = make([]byte, 4*fluidWidth*fluidHeight)
+	}
+
+	for j := 0; j < fluidHeight; j++ {
+		for i := 0; i < fluidWidth; i++ {
+			// Pixels are premultiplied by alpha
+			density := math.Min(1, v.fluid.Dye(i, j))
+			k := 4 * (j*fluidWidth + i)
+			v.fluidPixels[k] = uint8(float64(v.colorScheme.red) * density)
+			v.fluidPixels[k+1] = uint8(float64(v.colorScheme.green) * density)
+			v.fluidPixels[k+2] = uint8(float64(v.colorScheme.blue) * density)
+			v.fluidPixels[k+3] = uint8(255 * density)
+		}
+	}
+	v.fluidImage.WritePixels(v.fluidPixels)
+
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(float64(v.screenWidth)/fluidWidth, float64(v.screenHeight)/fluidHeight)
+	screen.DrawImage(v.fluidImage, op)
+}
+
+func newFluid() *fluid.Field {
+	f := fluid.New(fluidWidth, fluidHeight)
+	f.Viscosity = fluidViscosity
+	f.Diffusion = fluidDiffusion
+	f.Dissipation = fluidDissipation
+	return f
+}
@@ -0,0 +1,67 @@
+//go:build render
+
+package visualiser
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGoldenWaveforms(t *testing.T) {
+	for _, waveForm := range []string{"", "smooth", "ferroliquid", "bezier", "blob", "ridges", "terrain"} {
+		name := "waveform_" + waveForm
+		if waveForm == "" {
+			name = "waveform_none"
+		}
+		t.Run(name, func(t *testing.T) {
+			got := lastFrame(t, setup{name: name, waveForm: waveForm, ticks: 30, audio: toneAudio})
+			checkGolden(t, name, got)
+		})
+	}
+}
+
+func TestGoldenPatterns(t *testing.T) {
+	for _, pointType := range []string{"radial", "spiral", "slinky", "spikes", "flock"} {
+		name := "pattern_" + pointType
+		t.Run(name, func(t *testing.T) {
+			got := lastFrame(t, setup{name: name, pointType: pointType, ticks: 30, audio: toneAudio})
+			checkGolden(t, name, got)
+		})
+	}
+}
+
+func TestCompareImages(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	got := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	// A barely visible change stays within tolerance
+	got.Set(1, 1, color.RGBA{R: 3, G: 3, B: 3, A: 255})
+	want.Set(1, 1, color.RGBA{A: 255})
+	if _, ratio := compareImages(want, got); ratio != 0 {
+		t.Errorf("Near-identical images differ in %.2f of pixels", ratio)
+	}
+
+	// A bright pixel on black does not
+	got.Set(5, 5, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	if _, ratio := compareImages(want, got); ratio != 0.01 {
+		t.Errorf("Expected 1%% of pixels to differ, got %.2f%%", ratio*100)
+	}
+
+	// Mismatched sizes always fail
+	if _, ratio := compareImages(want, image.NewRGBA(image.Rect(0, 0, 5, 5))); ratio != 1 {
+		t.Errorf("Expected mismatched sizes to differ completely, got %.2f", ratio)
+	}
+}
+
+func TestRenderIsDeterministic(t *testing.T) {
+	s := setup{name: "determinism", waveForm: "ferroliquid", pointType: "spikes", ticks: 60, audio: toneAudio}
+	first := render(t, s)
+	second := render(t, s)
+	for tick := range first {
+		if !bytes.Equal(first[tick].Pix, second[tick].Pix) {
+			t.Fatalf("Two renders with the same seed and audio differ on tick %d", tick)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+//go:build render
+
+package visualiser
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	goldenDir      = "testdata/golden"
+	goldenWidth    = 320
+	goldenHeight   = 180
+	goldenSeed     = 1
+	pixelTolerance = 0.05  // Perceptual distance, 0 to 1, under which pixels count as equal
+	maxDiffRatio   = 0.005 // Share of pixels allowed to differ before a golden fails
+)
+
+var update = flag.Bool("update", false, "regenerate golden images in "+goldenDir)
+
+// harnessGame runs the tests inside the Ebiten loop so images can be read back.
+type harnessGame struct {
+	m    *testing.M
+	code int
+}
+
+func (g *harnessGame) Update() error {
+	g.code = g.m.Run()
+	return ebiten.Termination
+}
+
+func (*harnessGame) Draw(*ebiten.Image) {
+}
+
+func (*harnessGame) Layout(int, int) (int, int) {
+	return goldenWidth, goldenHeight
+}
+
+// Rendering tests need a window, so they only build with the render tag:
+//
+//	xvfb-run go test -tags render ./visualiser
+//
+// Pass -update to regenerate the golden images after an intended change.
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	g := &harnessGame{m: m, code: 1}
+	ebiten.SetWindowSize(goldenWidth, goldenHeight)
+	if err := ebiten.RunGameWithOptions(g, &ebiten.RunGameOptions{InitUnfocused: true, SkipTaskbar: true}); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	os.Exit(g.code)
+}
+
+// setup describes one deterministic render of the visualiser.
+type setup struct {
+	name      string
+	waveForm  string
+	pointType string
+	ticks     int
+	audio     func(tick int, samples []float64)
+}
+
+// render runs the setup for its ticks with a fixed seed, drawing after every
+// update like the game loop does, and returns the frame drawn on each tick.
+func render(t *testing.T, s setup) []*image.RGBA {
+	t.Helper()
+
+	v := newAudioVisualizer(chunkSize, goldenWidth, goldenHeight, goldenSeed, defaultTPS)
+	v.showText = false
+	v.waveForm = s.waveForm
+	v.pointType = s.pointType
+
+	screen := ebiten.NewImage(goldenWidth, goldenHeight)
+	defer screen.Deallocate()
+	frames := make([]*image.RGBA, s.ticks)
+	for tick := range frames {
+		s.audio(tick, v.currentChunk)
+		if err := v.Update(); err != nil {
+			t.Fatalf("Update failed on tick %d: %v", tick, err)
+		}
+		screen.Clear()
+		v.Draw(screen)
+
+		frames[tick] = image.NewRGBA(image.Rect(0, 0, goldenWidth, goldenHeight))
+		screen.ReadPixels(frames[tick].Pix)
+		opaque(frames[tick])
+	}
+	return frames
+}
+
+// lastFrame renders the setup and returns the frame drawn on its last tick.
+func lastFrame(t *testing.T, s setup) *image.RGBA {
+	t.Helper()
+	frames := render(t, s)
+	return frames[len(frames)-1]
+}
+
+// opaque sets every pixel fully opaque, as the window shows them. Blending
+// can leave colours brighter than their alpha, which a PNG can't hold.
+func opaque(img *image.RGBA) {
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+}
+
+// checkGolden compares got with the named golden image, or rewrites the
+// golden when the -update flag is set.
+func checkGolden(t *testing.T, name string, got *image.RGBA) {
+	t.Helper()
+	path := filepath.Join(goldenDir, name+".png")
+
+	if *update {
+		if err := writePNG(path, got); err != nil {
+			t.Fatalf("Failed to write golden: %v", err)
+		}
+		return
+	}
+
+	want, err := readPNG(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("No golden image at %s, run go test -tags render ./visualiser -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read golden: %v", err)
+	}
+
+	diff, ratio := compareImages(want, got)
+	if ratio > maxDiffRatio {
+		actualPath := filepath.Join(t.TempDir(), name+".actual.png")
+		diffPath := filepath.Join(t.TempDir(), name+".diff.png")
+		_ = writePNG(actualPath, got)
+		_ = writePNG(diffPath, diff)
+		t.Errorf("%s differs from golden in %.2f%% of pixels (allowed %.2f%%), see %s and %s",
+			name, ratio*100, maxDiffRatio*100, actualPath, diffPath)
+	}
+}
+
+// compareImages returns an image marking differing pixels in red and the
+// share of pixels whose perceptual distance exceeds pixelTolerance.
+func compareImages(want, got image.Image) (*image.RGBA, float64) {
+	bounds := want.Bounds()
+	if got.Bounds() != bounds {
+		return image.NewRGBA(got.Bounds()), 1
+	}
+
+	diff := image.NewRGBA(bounds)
+	differing := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			distance := perceptualDistance(want.At(x, y), got.At(x, y))
+			if distance > pixelTolerance {
+				differing++
+				diff.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				gray := uint8(luma(got.At(x, y)) * 64)
+				diff.Set(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+			}
+		}
+	}
+	return diff, float64(differing) / float64(bounds.Dx()*bounds.Dy())
+}
+
+// perceptualDistance is a weighted RGB distance between 0 and 1 that tracks
+// how different two colours look more closely than a plain channel delta.
+func perceptualDistance(a, b color.Color) float64 {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	dr := (float64(r1) - float64(r2)) / 0xffff
+	dg := (float64(g1) - float64(g2)) / 0xffff
+	db := (float64(b1) - float64(b2)) / 0xffff
+	da := (float64(a1) - float64(a2)) / 0xffff
+
+	// Red-mean weighting, see https://www.compuphase.com/cmetric.htm
+	redMean := (float64(r1) + float64(r2)) / 2 / 0xffff
+	distance := (2+redMean)*dr*dr + 4*dg*dg + (3-redMean)*db*db
+	return math.Sqrt(distance/9 + da*da)
+}
+
+func luma(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+}
+
+func readPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// toneAudio fills samples with a chord whose loudness swells over the ticks,
+// so both the waveforms and the point emitters have something to react to.
+func toneAudio(tick int, samples []float64) {
+	swell := 0.2 + 0.15*math.Sin(float64(tick)/5)
+	for i := range samples {
+		t := float64(tick*len(samples)+i) / sampleRate
+		samples[i] = swell * (math.Sin(2*math.Pi*220*t) + 0.5*math.Sin(2*math.Pi*330*t) + 0.25*math.Sin(2*math.Pi*440*t))
+	}
+}
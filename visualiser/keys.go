@@ -0,0 +1,273 @@
+package visualiser
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/idroz/mezmer/keymap"
+)
+
+const colorStep = 4 // Colour change per press, or per repeat while held
+
+// keyAction is something the performer can do from the keyboard or a
+// gamepad.
+type keyAction struct {
+	name    string
+	group   string // Line of the help listing it appears on
+	label   string
+	mode    keymap.Mode
+	keys    []string // Default bindings
+	overlay int      // Overlay cued, counting from 1, or 0 for other actions
+	do      func(v *audioVisualizer)
+}
+
+// keyActions are every key action, in the order they are listed in the help.
+var keyActions = []keyAction{
+	{name: "pattern-radial", group: "Patterns", label: "Radial", keys: []string{"5"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.pointType = "radial" }) }},
+	{name: "pattern-spiral", group: "Patterns", label: "Spiral", keys: []string{"6"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.pointType = "spiral" }) }},
+	{name: "pattern-slinky", group: "Patterns", label: "Slinky", keys: []string{"7"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.pointType = "slinky" }) }},
+	{name: "pattern-spikes", group: "Patterns", label: "Spikes", keys: []string{"8"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.pointType = "spikes" }) }},
+	{name: "pattern-flock", group: "Patterns", label: "Flock", keys: []string{"Shift+8"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.pointType = "flock" }) }},
+	{name: "forces", group: "Patterns", label: "Forces", keys: []string{"9", "GamepadY"}, do: func(v *audioVisualizer) {
+		v.applyForceSet((v.forceSet + 1) % len(forceSets))
+	}},
+	{name: "fluid", group: "Patterns", label: "Fluid", keys: []string{"L", "GamepadX"}, do: func(v *audioVisualizer) { v.fluidOn = !v.fluidOn }},
+
+	{name: "waveform-none", group: "Waveforms", label: "None", keys: []string{"0"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.waveForm = "" }) }},
+	{name: "waveform-smooth", group: "Waveforms", label: "Smooth", keys: []string{"1"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.waveForm = "smooth" }) }},
+	{name: "waveform-bezier", group: "Waveforms", label: "Bezier", keys: []string{"2"}, do: func(v *audioVisualizer) {
+		v.changeLook(func() { v.waveForm, v.fillWaveform = "bezier", false })
+	}},
+	{name: "waveform-filled-bezier", group: "Waveforms", label: "Filled Bezier", keys: []string{"Shift+2"}, do: func(v *audioVisualizer) {
+		v.changeLook(func() { v.waveForm, v.fillWaveform = "bezier", true })
+	}},
+	{name: "waveform-blob", group: "Waveforms", label: "Blob", keys: []string{"3"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.waveForm = "blob" }) }},
+	{name: "waveform-ridges", group: "Waveforms", label: "Ridges", keys: []string{"4"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.waveForm = "ridges" }) }},
+	{name: "waveform-terrain", group: "Waveforms", label: "Terrain", keys: []string{"Shift+4"}, do: func(v *audioVisualizer) { v.changeLook(func() { v.waveForm = "terrain" }) }},
+
+	{name: "harmonic", group: "Colour", label: "Harmonic", keys: []string{"H", "GamepadB"}, do: func(v *audioVisualizer) { v.harmonicColor = !v.harmonicColor }},
+	{name: "fifths", group: "Colour", label: "Fifths/Chromatic", keys: []string{"Shift+H"}, do: func(v *audioVisualizer) { v.fifthsOrder = !v.fifthsOrder }},
+	{name: "red-down", group: "Colour", label: "Less red", mode: keymap.Repeat, keys: []string{"R"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.red, -colorStep) }},
+	{name: "red-up", group: "Colour", label: "More red", mode: keymap.Repeat, keys: []string{"Shift+R"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.red, colorStep) }},
+	{name: "green-down", group: "Colour", label: "Less green", mode: keymap.Repeat, keys: []string{"G"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.green, -colorStep) }},
+	{name: "green-up", group: "Colour", label: "More green", mode: keymap.Repeat, keys: []string{"Shift+G"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.green, colorStep) }},
+	{name: "blue-down", group: "Colour", label: "Less blue", mode: keymap.Repeat, keys: []string{"B"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.blue, -colorStep) }},
+	{name: "blue-up", group: "Colour", label: "More blue", mode: keymap.Repeat, keys: []string{"Shift+B"}, do: func(v *audioVisualizer) { stepColor(&v.colorScheme.blue, colorStep) }},
+
+	{name: "preset-previous", group: "Presets", label: "Previous", keys: []string{"BracketLeft", "GamepadLB"}, do: func(v *audioVisualizer) { v.stepPreset(-1) }},
+	{name: "preset-next", group: "Presets", label: "Next", keys: []string{"BracketRight", "GamepadRB"}, do: func(v *audioVisualizer) { v.stepPreset(1) }},
+
+	{name: "hud", group: "Window", label: "HUD", keys: []string{"Space", "GamepadBack"}, do: func(v *audioVisualizer) { v.showText = !v.showText }},
+	{name: "fullscreen", group: "Window", label: "Fullscreen", keys: []string{"F"}, do: func(v *audioVisualizer) { ebiten.SetFullscreen(!ebiten.IsFullscreen()) }},
+	{name: "cursor", group: "Window", label: "Cursor", keys: []string{"C"}, do: func(v *audioVisualizer) {
+		if ebiten.CursorMode() == ebiten.CursorModeHidden {
+			ebiten.SetCursorMode(ebiten.CursorModeVisible)
+		} else {
+			ebiten.SetCursorMode(ebiten.CursorModeHidden)
+		}
+	}},
+
+	{name: "tilt-up", group: "Terrain", label: "Tilt up", mode: keymap.Hold, keys: []string{"ArrowUp", "GamepadUp"}, do: func(v *audioVisualizer) {
+		v.terrainTilt = min(maxTilt, v.terrainTilt+tiltStep)
+	}},
+	{name: "tilt-down", group: "Terrain", label: "Tilt down", mode: keymap.Hold, keys: []string{"ArrowDown", "GamepadDown"}, do: func(v *audioVisualizer) {
+		v.terrainTilt = max(0, v.terrainTilt-tiltStep)
+	}},
+	{name: "scroll-faster", group: "Terrain", label: "Faster", mode: keymap.Hold, keys: []string{"ArrowRight", "GamepadRight"}, do: func(v *audioVisualizer) {
+		v.terrainScroll = min(maxScroll, v.terrainScroll+scrollStep)
+	}},
+	{name: "scroll-slower", group: "Terrain", label: "Slower", mode: keymap.Hold, keys: []string{"ArrowLeft", "GamepadLeft"}, do: func(v *audioVisualizer) {
+		v.terrainScroll = max(0, v.terrainScroll-scrollStep)
+	}},
+
+	{name: "track-previous", group: "Setlist", label: "Previous track", keys: []string{"PageUp", "GamepadLT"}, do: func(v *audioVisualizer) {
+		v.stepTrack(-1)
+	}},
+	{name: "track-next", group: "Setlist", label: "Next track", keys: []string{"PageDown", "GamepadRT"}, do: func(v *audioVisualizer) {
+		v.stepTrack(1)
+	}},
+
+	{name: "autopilot", group: "Autopilot", label: "On/Off", keys: []string{"A", "GamepadA"}, do: func(v *audioVisualizer) { v.pilot.On = !v.pilot.On }},
+}
+
+// helpGroups are the lines of the help listing, from the bottom of the
+// screen up.
+var helpGroups = []string{"Patterns", "Waveforms", "Colour", "Presets", "Window", "Terrain", "Overlays", "Setlist", "Autopilot"}
+
+func init() {
+	// F1 to F12 cue the overlays in the order they are listed
+	for i := 1; i <= 12; i++ {
+		keyActions = append(keyActions, keyAction{
+			name:    fmt.Sprintf("overlay-%d", i),
+			group:   "Overlays",
+			keys:    []string{fmt.Sprintf("F%d", i)},
+			overlay: i,
+			do: func(v *audioVisualizer) {
+				if i <= len(v.overlays) {
+					v.cueOverlay(v.overlays[i-1].Name)
+				}
+			},
+		})
+	}
+}
+
+// gamepadButtons names the buttons of a standard gamepad, named after an
+// Xbox controller.
+var gamepadButtons = map[string]ebiten.StandardGamepadButton{
+	"GamepadA":          ebiten.StandardGamepadButtonRightBottom,
+	"GamepadB":          ebiten.StandardGamepadButtonRightRight,
+	"GamepadX":          ebiten.StandardGamepadButtonRightLeft,
+	"GamepadY":          ebiten.StandardGamepadButtonRightTop,
+	"GamepadLB":         ebiten.StandardGamepadButtonFrontTopLeft,
+	"GamepadRB":         ebiten.StandardGamepadButtonFrontTopRight,
+	"GamepadLT":         ebiten.StandardGamepadButtonFrontBottomLeft,
+	"GamepadRT":         ebiten.StandardGamepadButtonFrontBottomRight,
+	"GamepadBack":       ebiten.StandardGamepadButtonCenterLeft,
+	"GamepadStart":      ebiten.StandardGamepadButtonCenterRight,
+	"GamepadHome":       ebiten.StandardGamepadButtonCenterCenter,
+	"GamepadLeftStick":  ebiten.StandardGamepadButtonLeftStick,
+	"GamepadRightStick": ebiten.StandardGamepadButtonRightStick,
+	"GamepadUp":         ebiten.StandardGamepadButtonLeftTop,
+	"GamepadDown":       ebiten.StandardGamepadButtonLeftBottom,
+	"GamepadLeft":       ebiten.StandardGamepadButtonLeftLeft,
+	"GamepadRight":      ebiten.StandardGamepadButtonLeftRight,
+}
+
+// keyLabels are shorter names for keys in the help listing.
+var keyLabels = map[string]string{
+	"BracketLeft":  "[",
+	"BracketRight": "]",
+	"ArrowUp":      "Up",
+	"ArrowDown":    "Down",
+	"ArrowLeft":    "Left",
+	"ArrowRight":   "Right",
+	"Control":      "Ctrl",
+}
+
+// canonicalInput returns the name a key or gamepad button is recorded
+// under, accepting any name ebiten knows a key by, plus Ctrl and Cmd.
+func canonicalInput(name string) (string, error) {
+	for gamepad := range gamepadButtons {
+		if strings.EqualFold(name, gamepad) {
+			return gamepad, nil
+		}
+	}
+	switch strings.ToLower(name) {
+	case "ctrl":
+		name = "Control"
+	case "cmd":
+		name = "Meta"
+	}
+	var key ebiten.Key
+	if err := key.UnmarshalText([]byte(name)); err != nil {
+		return "", fmt.Errorf("unknown key or button %q", name)
+	}
+	return key.String(), nil
+}
+
+// newKeymap creates the map of key actions with their default bindings.
+func newKeymap() *keymap.Map {
+	m := keymap.New()
+	for _, a := range keyActions {
+		var bindings []keymap.Binding
+		for _, s := range a.keys {
+			b, err := keymap.ParseBinding(s, canonicalInput)
+			if err != nil {
+				panic(err)
+			}
+			bindings = append(bindings, b)
+		}
+		m.Add(a.name, a.mode, bindings...)
+	}
+	return m
+}
+
+// loadKeymap rebinds key actions from a JSON file of action names and
+// bindings.
+func (v *audioVisualizer) loadKeymap(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := v.keys.Load(f, canonicalInput); err != nil {
+		return fmt.Errorf("invalid keymap %s: %v", path, err)
+	}
+	return nil
+}
+
+// updateKeys performs the key actions fired this tick.
+func (v *audioVisualizer) updateKeys() {
+	v.gamepads = ebiten.AppendGamepadIDs(v.gamepads[:0])
+	v.keys.Update(v)
+	for _, a := range keyActions {
+		if v.keys.Fired(a.name) {
+			a.do(v)
+		}
+	}
+}
+
+// Duration returns how many ticks the named key or gamepad button has been
+// held, taken from the session when one is being replayed so the recorded
+// performance repeats exactly.
+func (v *audioVisualizer) Duration(input string) int {
+	if v.replay != nil {
+		return v.replay.held.Duration(input)
+	}
+	if button, ok := gamepadButtons[input]; ok {
+		longest := 0
+		for _, id := range v.gamepads {
+			longest = max(longest, inpututil.StandardGamepadButtonPressDuration(id, button))
+		}
+		return longest
+	}
+	var key ebiten.Key
+	if err := key.UnmarshalText([]byte(input)); err != nil {
+		return 0
+	}
+	return inpututil.KeyPressDuration(key)
+}
+
+// stepPreset moves through the presets by step.
+func (v *audioVisualizer) stepPreset(step int) {
+	if len(v.presets) > 0 {
+		v.applyPreset((v.currentPreset + step + len(v.presets)) % len(v.presets))
+	}
+}
+
+func stepColor(channel *int, step int) {
+	*channel = min(255, max(0, *channel+step))
+}
+
+// helpLines lists the bindings of the key actions, one line per group from
+// the bottom of the screen up.
+func (v *audioVisualizer) helpLines() []string {
+	var entries []keymap.Entry
+	for _, a := range keyActions {
+		if a.group == "Setlist" && v.setlist == nil || a.overlay > len(v.overlays) {
+			continue
+		}
+		label := a.label
+		if a.overlay > 0 {
+			label = v.overlays[a.overlay-1].Name
+		}
+		entries = append(entries, keymap.Entry{Action: a.name, Group: a.group, Label: label})
+	}
+	return v.keys.Help(helpGroups, entries, bindingLabel)
+}
+
+// bindingLabel writes a binding as it is shown in the help listing.
+func bindingLabel(b keymap.Binding) string {
+	parts := append(append([]string(nil), b.Modifiers...), b.Input)
+	for i, part := range parts {
+		if label, ok := keyLabels[part]; ok {
+			parts[i] = label
+		} else {
+			parts[i] = strings.TrimPrefix(part, "Digit")
+		}
+	}
+	return strings.Join(parts, "+")
+}
@@ -0,0 +1,94 @@
+package visualiser
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	previewWidth  = 640
+	previewHeight = 360
+)
+
+// offlineRenderer runs a replayed session as fast as possible, saving every
+// frame as a PNG while showing a small preview in the window.
+type offlineRenderer struct {
+	visualizer *audioVisualizer
+	dir        string
+	canvas     *ebiten.Image
+	frame      *image.RGBA
+	count      int
+}
+
+// renderOffline renders the replay attached to v into dir at width by height.
+func renderOffline(v *audioVisualizer, dir string, width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid render size %dx%d", width, height)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Render at the output size rather than the window size
+	v.screenWidth = width
+	v.screenHeight = height
+
+	r := &offlineRenderer{
+		visualizer: v,
+		dir:        dir,
+		canvas:     ebiten.NewImage(width, height),
+		frame:      image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+
+	// One update per frame, with no waiting for the display
+	ebiten.SetTPS(ebiten.SyncWithFPS)
+	ebiten.SetVsyncEnabled(false)
+	ebiten.SetWindowSize(previewWidth, previewHeight)
+	ebiten.SetWindowTitle("Mezmer (rendering)")
+	if err := ebiten.RunGame(r); err != nil {
+		return err
+	}
+	fmt.Printf("Rendered %d frames to %s\n", r.count, dir)
+	return nil
+}
+
+func (r *offlineRenderer) Update() error {
+	if err := r.visualizer.Update(); err != nil {
+		return err
+	}
+
+	r.canvas.Clear()
+	r.visualizer.Draw(r.canvas)
+	r.canvas.ReadPixels(r.frame.Pix)
+
+	file, err := os.Create(filepath.Join(r.dir, fmt.Sprintf("frame_%06d.png", r.count)))
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, r.frame); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	r.count++
+	return nil
+}
+
+func (r *offlineRenderer) Draw(screen *ebiten.Image) {
+	bounds := r.canvas.Bounds()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(previewWidth)/float64(bounds.Dx()), float64(previewHeight)/float64(bounds.Dy()))
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(r.canvas, op)
+}
+
+func (r *offlineRenderer) Layout(int, int) (int, int) {
+	return previewWidth, previewHeight
+}
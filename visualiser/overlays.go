@@ -0,0 +1,67 @@
+package visualiser
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const overlayHeight = 720 // Screen height at which overlays appear at their own size
+
+// cueOverlay shows the named overlay, or starts it fading out if it is
+// already showing.
+func (v *audioVisualizer) cueOverlay(name string) {
+	for _, layer := range v.overlays {
+		if layer.Name == name {
+			layer.Cue(v.tick)
+		}
+	}
+}
+
+// showOverlay shows the named overlay, leaving it be if it is already showing.
+func (v *audioVisualizer) showOverlay(name string) {
+	for _, layer := range v.overlays {
+		if layer.Name == name {
+			layer.Show(v.tick)
+		}
+	}
+}
+
+// updateOverlays moves the showing overlays along their timelines.
+func (v *audioVisualizer) updateOverlays() {
+	for _, layer := range v.overlays {
+		layer.Update(v.tick, v.featureLevel(layer.Pulse))
+	}
+}
+
+// drawOverlays draws the showing overlays over everything else.
+func (v *audioVisualizer) drawOverlays(screen *ebiten.Image) {
+	if len(v.overlayImages) != len(v.overlays) {
+		v.overlayImages = make([]*ebiten.Image, len(v.overlays))
+	}
+	screenScale := float64(v.screenHeight) / overlayHeight
+	for i, layer := range v.overlays {
+		x, y, scale, opacity := layer.Placement()
+		if !layer.Showing() || opacity <= 0 {
+			continue
+		}
+		if v.overlayImages[i] == nil {
+			v.overlayImages[i] = ebiten.NewImageFromImage(layer.Source)
+		}
+
+		img := v.overlayImages[i]
+		bounds := img.Bounds()
+		anchor := 0.5
+		switch layer.Align {
+		case "left":
+			anchor = 0
+		case "right":
+			anchor = 1
+		}
+		op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+		op.GeoM.Translate(-anchor*float64(bounds.Dx()), -0.5*float64(bounds.Dy()))
+		op.GeoM.Scale(scale*screenScale, scale*screenScale)
+		op.GeoM.Translate(x*float64(v.screenWidth), y*float64(v.screenHeight))
+		op.ColorScale.ScaleWithColor(layer.Tint)
+		op.ColorScale.ScaleAlpha(float32(opacity))
+		screen.DrawImage(img, op)
+	}
+}
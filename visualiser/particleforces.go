@@ -0,0 +1,135 @@
+package visualiser
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/idroz/mezmer/analysis"
+	"github.com/idroz/mezmer/forces"
+)
+
+const (
+	noiseSeed       = 1    // Turbulence is the same on every run, so it doesn't use up the random source
+	turbulenceSpeed = 0.01 // Eddies the turbulence drifts per tick
+	pointFade       = 0.005
+)
+
+// forceSpec is a force acting on the points, placed relative to the screen,
+// with its strength optionally following an audio feature.
+type forceSpec struct {
+	Kind     string  `json:"kind"`     // attractor, repulsor, vortex or turbulence
+	X        float64 `json:"x"`        // Centre as a fraction of the screen width
+	Y        float64 `json:"y"`        // Centre as a fraction of the screen height
+	Strength float64 `json:"strength"` // Acceleration in pixels per tick² at full level
+	Radius   float64 `json:"radius"`   // Falloff radius, or eddy size for turbulence, as a fraction of the screen height
+	Bind     string  `json:"bind"`     // volume, flux, brightness, noisiness or a band name to scale the strength by; empty for constant
+}
+
+// forceSet is a named arrangement of forces that can be cycled through.
+type forceSet struct {
+	Name     string
+	Forces   []forceSpec
+	Boundary forces.Boundary
+}
+
+var forceSets = []forceSet{
+	{Name: "none"},
+	{Name: "swirl", Boundary: forces.Wrap, Forces: []forceSpec{
+		{Kind: "vortex", X: 0.5, Y: 0.5, Strength: 0.15, Radius: 0.3, Bind: "bass"},
+		{Kind: "turbulence", Strength: 0.05, Radius: 0.2, Bind: "noisiness"},
+	}},
+	{Name: "gravity", Boundary: forces.Bounce, Forces: []forceSpec{
+		{Kind: "attractor", X: 0.5, Y: 0.5, Strength: 0.2, Radius: 0.4, Bind: "volume"},
+	}},
+	{Name: "storm", Boundary: forces.Wrap, Forces: []forceSpec{
+		{Kind: "turbulence", Strength: 0.3, Radius: 0.15, Bind: "flux"},
+		{Kind: "repulsor", X: 0.5, Y: 0.5, Strength: 0.1, Radius: 0.1, Bind: "sub"},
+	}},
+}
+
+// validateForces checks that every force is of a known kind and bound to a
+// feature featureLevel knows.
+func validateForces(specs []forceSpec) error {
+	for _, spec := range specs {
+		switch spec.Kind {
+		case "attractor", "repulsor", "vortex", "turbulence":
+		default:
+			return fmt.Errorf("unknown force %q", spec.Kind)
+		}
+		if !knownFeature(spec.Bind) {
+			return fmt.Errorf("unknown feature %q bound to %s", spec.Bind, spec.Kind)
+		}
+	}
+	return nil
+}
+
+// setForces replaces the forces acting on the points.
+func (v *audioVisualizer) setForces(specs []forceSpec, boundary forces.Boundary) {
+	v.forceSpecs = specs
+	v.boundary = boundary
+	v.forceField = v.forceField[:0]
+	for _, spec := range specs {
+		switch spec.Kind {
+		case "attractor", "repulsor":
+			v.forceField = append(v.forceField, &forces.Attractor{})
+		case "vortex":
+			v.forceField = append(v.forceField, &forces.Vortex{})
+		case "turbulence":
+			v.forceField = append(v.forceField, forces.NewTurbulence(noiseSeed, 0, 0, turbulenceSpeed))
+		}
+	}
+}
+
+// applyForceSet switches to the force set at index.
+func (v *audioVisualizer) applyForceSet(index int) {
+	v.forceSet = index
+	v.setForces(forceSets[index].Forces, forceSets[index].Boundary)
+}
+
+// updateForces places the forces on the current screen and sets their
+// strengths from the latest audio.
+func (v *audioVisualizer) updateForces() {
+	width, height := float64(v.screenWidth), float64(v.screenHeight)
+	for i, spec := range v.forceSpecs {
+		strength := spec.Strength * v.featureLevel(spec.Bind)
+		switch force := v.forceField[i].(type) {
+		case *forces.Attractor:
+			if spec.Kind == "repulsor" {
+				strength = -strength
+			}
+			force.X, force.Y, force.Strength, force.Radius = spec.X*width, spec.Y*height, strength, spec.Radius*height
+		case *forces.Vortex:
+			force.X, force.Y, force.Strength, force.Radius = spec.X*width, spec.Y*height, strength, spec.Radius*height
+		case *forces.Turbulence:
+			force.Strength, force.Scale = strength, spec.Radius*height
+		}
+	}
+}
+
+// knownFeature reports whether featureLevel knows the named feature.
+func knownFeature(name string) bool {
+	switch name {
+	case "", "volume", "flux", "brightness", "noisiness":
+		return true
+	}
+	return slices.ContainsFunc(analysis.DefaultBands, func(b analysis.Band) bool { return b.Name == name })
+}
+
+// featureLevel returns the named audio feature between 0 and 1, or 1 when
+// name is empty.
+func (v *audioVisualizer) featureLevel(name string) float64 {
+	switch name {
+	case "":
+		return 1
+	case "volume":
+		return math.Min(1, v.volume/4)
+	case "flux":
+		return v.frame.Normalized.Flux
+	case "brightness":
+		return v.frame.Normalized.Brightness()
+	case "noisiness":
+		return v.frame.Normalized.Noisiness()
+	}
+	return v.bandLevel(name)
+}
@@ -0,0 +1,90 @@
+package visualiser
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/idroz/mezmer/waveforms"
+)
+
+var (
+	whiteImage = ebiten.NewImage(3, 3)
+
+	// whiteSubImage is a solid white source for shapes coloured per vertex.
+	// The inner pixel avoids bleeding from the edges of the atlas.
+	whiteSubImage = whiteImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+)
+
+func init() {
+	whiteImage.Fill(color.White)
+}
+
+// fillPath fills the inside of path with clr.
+func fillPath(dst *ebiten.Image, path *vector.Path, clr color.RGBA, antialias bool, vertices []ebiten.Vertex, indices []uint16) ([]ebiten.Vertex, []uint16) {
+	vertices, indices = path.AppendVerticesAndIndicesForFilling(vertices[:0], indices[:0])
+	drawShape(dst, vertices, indices, clr, ebiten.FillRuleNonZero, antialias)
+	return vertices, indices
+}
+
+// strokePath outlines path with clr at width.
+func strokePath(dst *ebiten.Image, path *vector.Path, width float32, clr color.RGBA, antialias bool, vertices []ebiten.Vertex, indices []uint16) ([]ebiten.Vertex, []uint16) {
+	op := &vector.StrokeOptions{Width: width, LineJoin: vector.LineJoinRound, LineCap: vector.LineCapRound}
+	vertices, indices = path.AppendVerticesAndIndicesForStroke(vertices[:0], indices[:0], op)
+	drawShape(dst, vertices, indices, clr, ebiten.FillRuleFillAll, antialias)
+	return vertices, indices
+}
+
+// drawShape colours the triangles of a path with clr and draws them.
+func drawShape(dst *ebiten.Image, vertices []ebiten.Vertex, indices []uint16, clr color.RGBA, rule ebiten.FillRule, antialias bool) {
+	for i := range vertices {
+		vertices[i].SrcX = 1
+		vertices[i].SrcY = 1
+		vertices[i].ColorR = float32(clr.R) / 0xff
+		vertices[i].ColorG = float32(clr.G) / 0xff
+		vertices[i].ColorB = float32(clr.B) / 0xff
+		vertices[i].ColorA = float32(clr.A) / 0xff
+	}
+	dst.DrawTriangles(vertices, indices, whiteSubImage, &ebiten.DrawTrianglesOptions{FillRule: rule, AntiAlias: antialias})
+}
+
+// drawMesh draws triangles coloured per vertex by the waveforms package,
+// converting them into vertices, which it returns for reuse.
+func drawMesh(dst *ebiten.Image, mesh []waveforms.Vertex, indices []uint16, vertices []ebiten.Vertex) []ebiten.Vertex {
+	vertices = vertices[:0]
+	for _, m := range mesh {
+		vertices = append(vertices, ebiten.Vertex{
+			DstX: m.DstX, DstY: m.DstY,
+			SrcX: m.SrcX, SrcY: m.SrcY,
+			ColorR: m.ColorR, ColorG: m.ColorG, ColorB: m.ColorB, ColorA: m.ColorA,
+		})
+	}
+	dst.DrawTriangles(vertices, indices, whiteSubImage, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+	return vertices
+}
+
+// closedCurve returns a closed path passing through every point, with each
+// pair joined by the cubic Bézier equivalent of a Catmull-Rom segment.
+func closedCurve(points []waveforms.Point) *vector.Path {
+	var path vector.Path
+	n := len(points)
+	if n < 3 {
+		return &path
+	}
+
+	path.MoveTo(points[0].X, points[0].Y)
+	for i := 0; i < n; i++ {
+		p0 := points[(i+n-1)%n]
+		p1 := points[i]
+		p2 := points[(i+1)%n]
+		p3 := points[(i+2)%n]
+		path.CubicTo(
+			p1.X+(p2.X-p0.X)/6, p1.Y+(p2.Y-p0.Y)/6,
+			p2.X-(p3.X-p1.X)/6, p2.Y-(p3.Y-p1.Y)/6,
+			p2.X, p2.Y,
+		)
+	}
+	path.Close()
+	return &path
+}
@@ -0,0 +1,110 @@
+package visualiser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/idroz/mezmer/forces"
+	"github.com/idroz/mezmer/scene"
+	"github.com/idroz/mezmer/session"
+)
+
+// preset is a named scene that can be recalled while playing.
+type preset struct {
+	Name      string      `json:"name"`
+	WaveForm  string      `json:"waveform"`
+	Fill      bool        `json:"fill"`             // Fill the inside of closed waveforms
+	Tilt      *float64    `json:"tilt,omitempty"`   // Camera tilt of the ridges and terrain waveforms, in radians
+	Scroll    *float64    `json:"scroll,omitempty"` // Rows per tick the ridges and terrain scroll
+	PointType string      `json:"pattern"`
+	Fluid     bool        `json:"fluid"`    // Drift the points in the fluid layer
+	Forces    []forceSpec `json:"forces"`   // Forces acting on the points
+	Boundary  string      `json:"boundary"` // kill, wrap or bounce points at the edge of the screen
+	Red       int         `json:"red"`
+	Green     int         `json:"green"`
+	Blue      int         `json:"blue"`
+	Seed      *int64      `json:"seed,omitempty"` // Reseeds the random source when applied, so the scene replays exactly
+
+	Transition     string  `json:"transition"`     // cut, fade, dissolve, wipe or zoom into this preset
+	TransitionTime float64 `json:"transitionTime"` // Seconds the transition takes
+	OnBeat         bool    `json:"onBeat"`         // Wait for the next beat before changing
+}
+
+// loadPresets reads a JSON array of presets from path.
+func loadPresets(path string) ([]preset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var presets []preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("invalid preset file %s: %v", path, err)
+	}
+	for i, p := range presets {
+		if p.Name == "" {
+			presets[i].Name = fmt.Sprintf("Preset %d", i+1)
+		}
+		if err := validateForces(p.Forces); err != nil {
+			return nil, fmt.Errorf("invalid preset %s in %s: %v", presets[i].Name, path, err)
+		}
+		if _, err := forces.ParseBoundary(p.Boundary); err != nil {
+			return nil, fmt.Errorf("invalid preset %s in %s: %v", presets[i].Name, path, err)
+		}
+		if err := scene.ValidateKind(p.Transition); err != nil {
+			return nil, fmt.Errorf("invalid preset %s in %s: %v", presets[i].Name, path, err)
+		}
+	}
+	return presets, nil
+}
+
+func presetNames(presets []preset) []string {
+	names := make([]string, len(presets))
+	for i, p := range presets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// applyPreset switches the scene to the preset at index, on the next beat
+// if the preset asks for it.
+func (v *audioVisualizer) applyPreset(index int) {
+	if v.presets[index].OnBeat {
+		v.pending.Wait(index, v.tick)
+		return
+	}
+	v.switchPreset(index, v.presets[index].Transition)
+}
+
+// switchPreset switches the scene to the preset at index now, moving from
+// the current scene by the kind of transition given.
+func (v *audioVisualizer) switchPreset(index int, kind string) {
+	p := v.presets[index]
+	from := v.currentLook()
+	v.currentPreset = index
+	v.waveForm = p.WaveForm
+	v.fillWaveform = p.Fill
+	if p.Tilt != nil {
+		v.terrainTilt = *p.Tilt
+	}
+	if p.Scroll != nil {
+		v.terrainScroll = *p.Scroll
+	}
+	v.pointType = p.PointType
+	v.fluidOn = p.Fluid
+	boundary, _ := forces.ParseBoundary(p.Boundary)
+	v.setForces(p.Forces, boundary)
+	v.forceSet = -1
+	v.colorScheme = colorSceme{red: p.Red, green: p.Green, blue: p.Blue}
+	v.beginTransition(from, kind, p.TransitionTime)
+	v.pilot.SceneChanged(v.tick)
+	if p.Seed != nil {
+		v.rng = rand.New(rand.NewSource(*p.Seed))
+		v.seed = *p.Seed
+	}
+	if v.recorder != nil {
+		v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.PresetEvent, Name: p.Name, Value: float64(index)})
+	}
+}
@@ -0,0 +1,83 @@
+package visualiser
+
+import (
+	"errors"
+	"io"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/idroz/mezmer/control"
+	"github.com/idroz/mezmer/keymap"
+	"github.com/idroz/mezmer/session"
+)
+
+// replay feeds a recorded session into the visualiser in place of live audio
+// and keyboard input.
+type replay struct {
+	reader *session.Reader
+	held   keymap.Held // Keys and gamepad buttons held in the session
+	done   bool
+}
+
+// stepReplay applies the next tick of the replayed session. It returns
+// ebiten.Termination once the session has ended.
+func (v *audioVisualizer) stepReplay() error {
+	if v.replay.done {
+		return ebiten.Termination
+	}
+
+	step, err := v.replay.reader.Next()
+	if errors.Is(err, io.EOF) {
+		v.replay.done = true
+		return ebiten.Termination
+	}
+	if err != nil {
+		return err
+	}
+
+	v.input.Write(step.Audio)
+	for _, event := range step.Events {
+		if event.Kind == session.ControlEvent {
+			v.applyControl(control.Action{Name: event.Name, Text: event.Text, Value: event.Value})
+			continue
+		}
+		// Preset changes are logged for reference, the keys or actions that caused them replay them
+		if event.Kind != session.KeyEvent {
+			continue
+		}
+		name, err := canonicalInput(event.Name)
+		if err != nil {
+			continue
+		}
+		if event.Value > 0 {
+			v.replay.held.Press(name)
+		} else {
+			v.replay.held.Release(name)
+		}
+	}
+	v.replay.held.Tick()
+	return nil
+}
+
+// recordKeys writes every key and gamepad button pressed or released since
+// the last tick.
+func (v *audioVisualizer) recordKeys() {
+	v.keyBuffer = inpututil.AppendJustPressedKeys(v.keyBuffer[:0])
+	for _, key := range v.keyBuffer {
+		v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.KeyEvent, Name: key.String(), Value: 1})
+	}
+	v.keyBuffer = inpututil.AppendJustReleasedKeys(v.keyBuffer[:0])
+	for _, key := range v.keyBuffer {
+		v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.KeyEvent, Name: key.String(), Value: 0})
+	}
+	for _, id := range v.gamepads {
+		for name, button := range gamepadButtons {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.KeyEvent, Name: name, Value: 1})
+			}
+			if inpututil.IsStandardGamepadButtonJustReleased(id, button) {
+				v.recorder.WriteEvent(session.Event{Tick: v.tick, Kind: session.KeyEvent, Name: name, Value: 0})
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package visualiser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+
+	"github.com/idroz/mezmer/control"
+	"github.com/idroz/mezmer/midi"
+)
+
+// show lets a setlist change the scene.
+type show struct {
+	*audioVisualizer
+}
+
+func (s show) ApplyPreset(name string) {
+	s.applyPreset(presetIndex(s.presets, name))
+}
+
+func (s show) ShowOverlay(name string) {
+	s.showOverlay(name)
+}
+
+func (s show) CueOverlay(name string) {
+	s.cueOverlay(name)
+}
+
+func (s show) Automate(parameter string, value float64) {
+	switch parameter {
+	case "red":
+		s.colorScheme.red = min(255, max(0, int(value)))
+	case "green":
+		s.colorScheme.green = min(255, max(0, int(value)))
+	case "blue":
+		s.colorScheme.blue = min(255, max(0, int(value)))
+	case "tilt":
+		s.terrainTilt = value
+	case "scroll":
+		s.terrainScroll = value
+	}
+}
+
+func presetIndex(presets []preset, name string) int {
+	for i, p := range presets {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// updateSetlist plays the setlist on by a tick, moving to the track picked
+// by any MIDI program change first.
+func (v *audioVisualizer) updateSetlist() {
+	// Program changes are performed like control surface actions, so they are recorded
+	if v.programs != nil {
+	drain:
+		for {
+			select {
+			case program := <-v.programs:
+				v.perform(control.Action{Name: "track", Value: float64(program)})
+			default:
+				break drain
+			}
+		}
+	}
+	v.setlist.Update(v.tick, show{v})
+}
+
+// stepTrack moves the show step tracks on, or back when step is negative.
+func (v *audioVisualizer) stepTrack(step int) {
+	if v.setlist != nil {
+		v.setlist.Step(step, v.tick, show{v})
+	}
+}
+
+// readPrograms sends the program numbers of the program changes read from
+// the MIDI device at path to programs until ctx is cancelled.
+func readPrograms(ctx context.Context, path string, programs chan<- int) error {
+	device, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		device.Close()
+	}()
+
+	go func() {
+		decoder := midi.NewDecoder(device)
+		for {
+			message, err := decoder.Next()
+			if err != nil {
+				if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+					log.Printf("Stopped reading MIDI from %s: %v", path, err)
+				}
+				return
+			}
+			if message.Kind() != midi.ProgramChange {
+				continue
+			}
+			select {
+			case programs <- int(message.Data[0]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,31 @@
+package visualiser
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/waveforms"
+)
+
+const (
+	terrainColumns = 64
+	terrainDepth   = 48
+	terrainHeight  = 0.5  // Camera height above the landscape
+	defaultTilt    = 0.15 // Camera pitch in radians
+	defaultScroll  = 0.5  // Rows the landscape scrolls per tick
+	maxTilt        = 1.2
+	maxScroll      = 4
+	tiltStep       = 0.005 // Tilt change per tick while an arrow key is held
+	scrollStep     = 0.01
+)
+
+// drawTerrain draws the spectrum history as ridges or a wireframe landscape.
+func (v *audioVisualizer) drawTerrain(screen *ebiten.Image, style waveforms.TerrainStyle, strokeScale float32) {
+	line := color.RGBA{R: uint8(v.colorScheme.red), G: uint8(v.colorScheme.green), B: uint8(v.colorScheme.blue), A: 0xff}
+	camera := waveforms.Camera{Height: terrainHeight, Tilt: v.terrainTilt}
+	width := 1.5 * float64(strokeScale)
+
+	v.meshVertices, v.pathIndices = v.terrain.AppendMesh(v.meshVertices[:0], v.pathIndices[:0],
+		v.screenWidth, v.screenHeight, camera, style, line, color.RGBA{A: 0xff}, width)
+	v.pathVertices = drawMesh(screen, v.meshVertices, v.pathIndices, v.pathVertices)
+}
@@ -0,0 +1,134 @@
+package visualiser
+
+import (
+	"embed"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/idroz/mezmer/scene"
+)
+
+const manualTransitionTime = 0.5 // Seconds a waveform or pattern picked by hand takes to dissolve in
+
+//go:embed shaders/*.kage
+var shaderFiles embed.FS
+
+func (v *audioVisualizer) currentLook() scene.Look {
+	return scene.Look{
+		WaveForm: v.waveForm,
+		Fill:     v.fillWaveform,
+		Pattern:  v.pointType,
+		Red:      v.colorScheme.red,
+		Green:    v.colorScheme.green,
+		Blue:     v.colorScheme.blue,
+		Fluid:    v.fluidOn,
+		Tilt:     v.terrainTilt,
+		Scroll:   v.terrainScroll,
+	}
+}
+
+func (v *audioVisualizer) setLook(l scene.Look) {
+	v.waveForm = l.WaveForm
+	v.fillWaveform = l.Fill
+	v.pointType = l.Pattern
+	v.colorScheme = colorSceme{red: l.Red, green: l.Green, blue: l.Blue}
+	v.fluidOn = l.Fluid
+	v.terrainTilt = l.Tilt
+	v.terrainScroll = l.Scroll
+}
+
+// beginTransition starts moving from the look from to the current one.
+func (v *audioVisualizer) beginTransition(from scene.Look, kind string, duration float64) {
+	v.transition = scene.Begin(from, v.currentLook(), kind, duration, v.tick, v.tps)
+	if v.transition != nil && v.transition.Morph {
+		v.setLook(from)
+	}
+}
+
+// changeLook makes a change of waveform or pattern picked by hand,
+// dissolving from the old one into the new.
+func (v *audioVisualizer) changeLook(change func()) {
+	from := v.currentLook()
+	change()
+	if v.currentLook() != from {
+		v.beginTransition(from, "dissolve", manualTransitionTime)
+	}
+}
+
+// updateTransition moves a transition on, blending the parameters when
+// morphing, and ends it once it is done.
+func (v *audioVisualizer) updateTransition() {
+	t := v.transition
+	if t == nil {
+		return
+	}
+	if t.Morph {
+		v.setLook(t.At(v.tick))
+	}
+	if t.Done(v.tick) {
+		v.transition = nil
+	}
+}
+
+// updatePendingPreset applies a preset waiting for a beat once an onset
+// arrives, or after waiting too long for one.
+func (v *audioVisualizer) updatePendingPreset() {
+	if index, ok := v.pending.Ready(v.tick, v.onset); ok {
+		v.switchPreset(index, v.presets[index].Transition)
+	}
+}
+
+// drawTransition draws the old and new scenes offscreen and blends them on
+// screen with the transition's shader.
+func (v *audioVisualizer) drawTransition(screen *ebiten.Image) {
+	t := v.transition
+	shader, err := v.transitionShader(t.Kind)
+	if err != nil {
+		log.Printf("Failed to compile the %s transition: %v", t.Kind, err)
+		v.transition = nil
+		v.drawScene(screen)
+		return
+	}
+
+	bounds := screen.Bounds()
+	if v.sceneFrom == nil || v.sceneFrom.Bounds().Size() != bounds.Size() {
+		if v.sceneFrom != nil {
+			v.sceneFrom.Deallocate()
+			v.sceneTo.Deallocate()
+		}
+		v.sceneFrom = ebiten.NewImage(bounds.Dx(), bounds.Dy())
+		v.sceneTo = ebiten.NewImage(bounds.Dx(), bounds.Dy())
+	}
+
+	current := v.currentLook()
+	v.setLook(t.From)
+	v.drawScene(v.sceneFrom)
+	v.setLook(current)
+	v.drawScene(v.sceneTo)
+
+	op := &ebiten.DrawRectShaderOptions{}
+	op.Images[0] = v.sceneFrom
+	op.Images[1] = v.sceneTo
+	op.Uniforms = map[string]any{"Progress": float32(t.Progress(v.tick))}
+	screen.DrawRectShader(bounds.Dx(), bounds.Dy(), shader, op)
+}
+
+// transitionShader returns the shader for kind, compiling it the first time.
+func (v *audioVisualizer) transitionShader(kind string) (*ebiten.Shader, error) {
+	if shader, ok := v.shaders[kind]; ok {
+		return shader, nil
+	}
+	src, err := shaderFiles.ReadFile("shaders/" + kind + ".kage")
+	if err != nil {
+		return nil, err
+	}
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, err
+	}
+	if v.shaders == nil {
+		v.shaders = make(map[string]*ebiten.Shader)
+	}
+	v.shaders[kind] = shader
+	return shader, nil
+}
@@ -3,18 +3,32 @@ package visualiser
 import (
 	"context"
 	"fmt"
+	"image"
 	"image/color"
 	"log"
 	"math"
 	"math/rand"
+	"os"
 	"runtime"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/gen2brain/malgo"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/idroz/mezmer/analysis"
+	"github.com/idroz/mezmer/audio"
+	"github.com/idroz/mezmer/autopilot"
+	"github.com/idroz/mezmer/control"
+	"github.com/idroz/mezmer/flock"
+	"github.com/idroz/mezmer/fluid"
+	"github.com/idroz/mezmer/forces"
+	"github.com/idroz/mezmer/keymap"
+	"github.com/idroz/mezmer/overlay"
+	"github.com/idroz/mezmer/scene"
+	"github.com/idroz/mezmer/session"
+	"github.com/idroz/mezmer/setlist"
 	"github.com/idroz/mezmer/utils"
 	"github.com/idroz/mezmer/waveforms"
 	"golang.org/x/image/font/basicfont"
@@ -29,6 +43,9 @@ const (
 	smoothingFactor = 0.02
 	amplitudeFactor = 0.01 // Reduce sensitivity of amplitude changes
 	centerMoveSpeed = 0.2
+	sampleRate      = 44100
+	defaultTPS      = 60  // Ebiten ticks per second unless -tps is given
+	sparkleDecay    = 0.9 // Fraction of sparkle kept each tick
 )
 
 type point struct {
@@ -38,6 +55,7 @@ type point struct {
 	alpha     float64
 	volume    float64
 	fadeIn    bool
+	sparkle   float64
 	red       int
 	green     int
 	blue      int
@@ -61,15 +79,76 @@ type audioVisualizer struct {
 	showText        bool
 	volume          float64
 	frequency       float64
-	spacePressed    bool
 	waveForm        string
 	pointType       string
 	waveOffset      float64
 	connectedDevice string
 	colorScheme     colorSceme
+	input           *audio.Ring          // Source of live audio, nil when samples are fed directly
+	devices         *audio.DeviceManager // Capture device state, nil unless capturing from a device
+	awaiting        string               // What capture waits for while no device is present
+	rng             *rand.Rand           // Every random choice goes through here, so a seed replays exactly
+	seed            int64                // Seed of rng, mixed with the tick for randomness while drawing
+	drawRng         *rand.Rand           // Reseeded for every scene drawn, so drawing never touches rng
+	presets         []preset
+	currentPreset   int
+	tick            int64           // Number of updates so far
+	recorder        *session.Writer // Session being recorded, if any
+	replay          *replay         // Session being replayed, if any
+	keyBuffer       []ebiten.Key
+	analyzer        *analysis.Analyzer
+	frame           *analysis.Frame  // Analysis of the latest audio
+	countBand       string           // Band that scales the number of particles
+	sparkleBand     string           // Band that makes particles flash
+	strokeBand      string           // Band that thickens the waveform stroke
+	harmonicColor   bool             // Derive the colour scheme from the current chord or key
+	fifthsOrder     bool             // Order hues around the circle of fifths instead of chromatically
+	placement       *windowPlacement // Window placement saved on exit, nil when not in a window
+	done            <-chan struct{}  // Closed when the run is cancelled, nil when it can't be
+	control         *control.Server  // Performer's control surface, if any
+	actions         []control.Action
+	thumbnail       *ebiten.Image // Preview of the output for the control surface
+	thumbnailPixels *image.RGBA
+	thumbnailTick   int64
+	fillWaveform    bool // Fill the inside of closed waveforms
+	pathVertices    []ebiten.Vertex
+	pathIndices     []uint16
+	meshVertices    []waveforms.Vertex
+	terrain         *waveforms.Terrain // History of spectra for the ridges and terrain waveforms
+	terrainTilt     float64
+	terrainScroll   float64
+	fluid           *fluid.Field // Flow the particles drift in when fluidOn is set
+	fluidOn         bool
+	fluidImage      *ebiten.Image
+	fluidPixels     []byte
+	lastFlux        float64
+	onset           bool // Spectral flux rose past the onset threshold this tick
+	forceSpecs      []forceSpec
+	forceField      forces.Field    // Forces acting on the points, matching forceSpecs
+	boundary        forces.Boundary // What happens to points leaving the screen
+	forceSet        int             // Index into forceSets, -1 when set by a preset
+	flock           *flock.Flock    // Boids of the flock pattern, created when it is first shown
+	sustain         float64         // How long a note has been held, from 0 to 1
+	heldNote        int             // MIDI note of the latest pitch
+	lastBass        float64
+	overlays        []*overlay.Layer  // Titles and logos cued over the visuals
+	overlayImages   []*ebiten.Image   // Overlay sources uploaded for drawing, made when first shown
+	setlist         *setlist.Setlist  // Show being played, if any
+	programs        chan int          // MIDI program changes, nil without a MIDI device
+	transition      *scene.Transition // Change of scene in progress, if any
+	sceneFrom       *ebiten.Image
+	sceneTo         *ebiten.Image
+	shaders         map[string]*ebiten.Shader
+	pending         *scene.Pending   // Preset waiting for the next beat
+	pilot           *autopilot.Pilot // Changes scenes by itself when switched on
+	pilotFeatures   []float64
+	keys            *keymap.Map // Bindings of the key actions
+	tps             float64     // Ticks per second, the rate every timed change counts in
+	gamepads        []ebiten.GamepadID
 }
 
-func newAudioVisualizer(chunkSize, screenWidth, screenHeight int) *audioVisualizer {
+func newAudioVisualizer(chunkSize, screenWidth, screenHeight int, seed int64, tps float64) *audioVisualizer {
+	analyzer := analysis.NewAnalyzer(analysis.DefaultBands, sampleRate, tps)
 	return &audioVisualizer{
 		samples:         make([]float64, chunkSize),
 		currentChunk:    make([]float64, chunkSize),
@@ -81,77 +160,100 @@ func newAudioVisualizer(chunkSize, screenWidth, screenHeight int) *audioVisualiz
 		showText:        true,
 		volume:          0,
 		frequency:       0,
-		spacePressed:    false,
 		waveForm:        "smooth",
 		pointType:       "radial",
 		waveOffset:      0,
 		connectedDevice: "No Device",
+		rng:             rand.New(rand.NewSource(seed)),
+		seed:            seed,
+		drawRng:         rand.New(rand.NewSource(seed)),
+		currentPreset:   -1,
+		pending:         scene.NewPending(tps),
 		colorScheme:     colorSceme{red: 255, green: 0, blue: 255},
+		analyzer:        analyzer,
+		frame:           analyzer.Frame(),
+		countBand:       "bass",
+		sparkleBand:     "air",
+		strokeBand:      "lowmid",
+		harmonicColor:   false,
+		fifthsOrder:     true,
+		terrain:         waveforms.NewTerrain(terrainColumns, terrainDepth),
+		terrainTilt:     defaultTilt,
+		terrainScroll:   defaultScroll,
+		fluid:           newFluid(),
+		pilot:           autopilot.NewPilot(tps),
+		keys:            newKeymap(),
+		tps:             tps,
 	}
 }
 
 // Update reads new audio data into the visualizer and updates the points.
 func (v *audioVisualizer) Update() error {
-	// Handle toggling text visibility on space key press
-	if ebiten.IsKeyPressed(ebiten.KeySpace) {
-		if !v.spacePressed {
-			v.showText = !v.showText
-			v.spacePressed = true
-		}
-	} else {
-		v.spacePressed = false
-	}
+	defer func() { v.tick++ }()
 
-	if ebiten.IsKeyPressed(ebiten.KeyDigit0) {
-		v.waveForm = ""
+	if ebiten.IsWindowBeingClosed() || v.cancelled() {
+		v.trackWindow()
+		return ebiten.Termination
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyDigit1) {
-		v.waveForm = "smooth"
+	if v.tick%int64(v.tps) == 0 {
+		v.trackWindow()
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyDigit5) {
-		v.pointType = "radial"
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyDigit6) {
-		v.pointType = "spiral"
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyDigit7) {
-		v.pointType = "slinky"
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyDigit8) {
-		v.pointType = "spikes"
-	}
-
-	if ebiten.IsKeyPressed(ebiten.KeyShift) && ebiten.IsKeyPressed(ebiten.KeyR) {
-		v.colorScheme.red = int(math.Min(255, float64(v.colorScheme.red+1)))
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyR) && (!ebiten.IsKeyPressed(ebiten.KeyShift)) {
-		v.colorScheme.red = int(math.Max(0, float64(v.colorScheme.red-1)))
+	if v.replay != nil {
+		if err := v.stepReplay(); err != nil {
+			return err
+		}
+	} else if v.recorder != nil {
+		v.recordKeys()
 	}
 
-	if ebiten.IsKeyPressed(ebiten.KeyShift) && ebiten.IsKeyPressed(ebiten.KeyG) {
-		v.colorScheme.green = int(math.Min(255, float64(v.colorScheme.green+1)))
-	}
-	if ebiten.IsKeyPressed(ebiten.KeyG) && (!ebiten.IsKeyPressed(ebiten.KeyShift)) {
-		v.colorScheme.green = int(math.Max(0, float64(v.colorScheme.green-1)))
-	}
+	v.updateKeys()
 
-	if ebiten.IsKeyPressed(ebiten.KeyShift) && ebiten.IsKeyPressed(ebiten.KeyB) {
-		v.colorScheme.blue = int(math.Min(255, float64(v.colorScheme.blue+1)))
+	if v.control != nil {
+		v.updateControls()
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyB) && (!ebiten.IsKeyPressed(ebiten.KeyShift)) {
-		v.colorScheme.blue = int(math.Max(0, float64(v.colorScheme.blue-1)))
+	if v.setlist != nil {
+		v.updateSetlist()
 	}
 
 	// Copy the latest audio data into the visualizer's current chunk.
+	if v.input != nil {
+		position := v.input.Latest(v.currentChunk)
+		if v.recorder != nil {
+			v.recorder.WriteTick(v.tick, position)
+		}
+	}
 	copy(v.samples, v.currentChunk)
 
-	// Compute the RMS (volume) of the current chunk
-	sumSquares := 0.0
-	for _, sample := range v.currentChunk {
-		sumSquares += sample * sample
+	return v.advance(v.analyzer.Process(v.samples))
+}
+
+// cancelled reports whether the run was cancelled, such as by an interrupt.
+func (v *audioVisualizer) cancelled() bool {
+	select {
+	case <-v.done:
+		return true
+	default:
+		return false
 	}
-	volume := math.Sqrt(sumSquares/float64(len(v.currentChunk))) * 15
+}
+
+// advance moves the points on by one tick, driven by the analysis of the latest audio.
+func (v *audioVisualizer) advance(frame *analysis.Frame) error {
+	v.frame = frame
+	v.onset = frame.Normalized.Flux > onsetThreshold && v.lastFlux <= onsetThreshold
+	v.lastFlux = frame.Normalized.Flux
+	v.updatePilot()
+	v.updatePendingPreset()
+	v.updateTransition()
+	v.terrain.Advance(frame.Spectrum, v.terrainScroll)
+	v.stepFluid()
+	v.updateForces()
+	v.stepFlock()
+	v.updateOverlays()
+
+	// Scale the RMS of the current chunk into a volume
+	volume := frame.RMS * 15
 
 	// Normalize volume to a range for better control
 	normalizedVolume := volume
@@ -161,27 +263,34 @@ func (v *audioVisualizer) Update() error {
 		v.volume -= (normalizedVolume - v.volume) * 0.5
 	}
 
-	// Adjust maxPoints based on normalized volume
-	v.maxPoints = int(normalizedVolume * 500) // Scale normalized volume to a reasonable number of points
+	if v.harmonicColor {
+		v.applyHarmonicColor()
+	}
+
+	// Adjust maxPoints based on normalized volume, scaled up or down by the count band
+	v.maxPoints = int(normalizedVolume * 500 * (0.5 + v.bandLevel(v.countBand))) // Scale normalized volume to a reasonable number of points
 	if v.maxPoints > 1000 {
 		v.maxPoints = 1000
 	} else if v.maxPoints < 3 {
 		v.maxPoints = 0
 	}
 
+	// Noisier sounds scatter the points more
+	variance := radiateVariance * (1 + 10*frame.Normalized.Noisiness())
+
 	randX := float64(v.screenWidth / 2)
 	randY := float64(v.screenHeight / 2)
 
 	if v.volume >= 4 {
-		randX = rand.Float64() * float64(v.screenWidth)
-		randY = rand.Float64() * float64(v.screenHeight)
+		randX = v.rng.Float64() * float64(v.screenWidth)
+		randY = v.rng.Float64() * float64(v.screenHeight)
 	}
 
 	if v.pointType == "radial" {
 		// Add new points radiating from the center of the screen based on the volume
 		for len(v.volumePoints) < v.maxPoints {
-			angle := rand.Float64() * 2 * math.Pi // Random angle
-			speed := normalizedVolume + rand.Float64()*radiateVariance
+			angle := v.rng.Float64() * 2 * math.Pi // Random angle
+			speed := normalizedVolume + v.rng.Float64()*variance
 			v.volumePoints = append(v.volumePoints, point{
 				x:         randX,
 				y:         randY,
@@ -199,7 +308,7 @@ func (v *audioVisualizer) Update() error {
 			radius := 0.1 * angle                       // Archimedean spiral: radius increases linearly with angle
 
 			// Compute initial position and velocity for the Archimedean spiral
-			speed := normalizedVolume*5 + rand.Float64()*radiateVariance
+			speed := normalizedVolume*5 + v.rng.Float64()*variance
 			xVelocity := math.Cos(angle) * speed
 			yVelocity := math.Sin(angle) * speed
 
@@ -217,7 +326,7 @@ func (v *audioVisualizer) Update() error {
 	} else if v.pointType == "slinky" {
 		// Add new points radiating in a spiral pattern based on the volume
 		for len(v.volumePoints) < v.maxPoints {
-			angle := rand.Float64() * 2 * math.Pi   // Random initial angle
+			angle := v.rng.Float64() * 2 * math.Pi  // Random initial angle
 			spiralRadius := normalizedVolume * 10.0 // Spiral radius influenced by volume
 			angleIncrement := 0.1                   // Controls the spacing of the spiral
 
@@ -232,8 +341,8 @@ func (v *audioVisualizer) Update() error {
 				v.volumePoints = append(v.volumePoints, point{
 					x:         randX + xOffset,
 					y:         randY + yOffset,
-					xVelocity: math.Cos(angle) * (normalizedVolume + rand.Float64()*radiateVariance),
-					yVelocity: math.Sin(angle) * (normalizedVolume + rand.Float64()*radiateVariance),
+					xVelocity: math.Cos(angle) * (normalizedVolume + v.rng.Float64()*variance),
+					yVelocity: math.Sin(angle) * (normalizedVolume + v.rng.Float64()*variance),
 					alpha:     0.0, // Start with alpha 0 for fade-in effect
 					volume:    normalizedVolume,
 					fadeIn:    true,
@@ -246,22 +355,22 @@ func (v *audioVisualizer) Update() error {
 			branchCount := int(math.Max(1, normalizedVolume*5))
 
 			// Randomly select one of the branches
-			branchIndex := rand.Intn(branchCount)
+			branchIndex := v.rng.Intn(branchCount)
 			baseAngle := (2 * math.Pi / float64(branchCount)) * float64(branchIndex)
 
 			// Add random deviation from the branch angle
-			angleDeviation := (rand.Float64() - 0.5) * math.Pi / 12 // Small deviation for natural look
+			angleDeviation := (v.rng.Float64() - 0.5) * math.Pi / 12 // Small deviation for natural look
 			angle := baseAngle + angleDeviation
 
 			// Generate random distance from the center
-			distance := rand.Float64() * normalizedVolume * 10.0
+			distance := v.rng.Float64() * normalizedVolume * 10.0
 
 			// Calculate point coordinates
 			randX := randX + math.Cos(angle)*distance
 			randY := randY + math.Sin(angle)*distance
 
 			// Speed calculation (same as before)
-			speed := normalizedVolume + rand.Float64()*radiateVariance
+			speed := normalizedVolume + v.rng.Float64()*variance
 
 			// Add the point to the list
 			v.volumePoints = append(v.volumePoints, point{
@@ -276,23 +385,50 @@ func (v *audioVisualizer) Update() error {
 		}
 	}
 
+	// A strong hit in the sparkle band lights up a share of the points
+	sparkle := v.bandLevel(v.sparkleBand)
+
 	// Update existing points (radiate, fade in, fade out, and remove if off screen or alpha <= 0)
 	for i := 0; i < len(v.volumePoints); i++ {
+		if len(v.forceField) > 0 {
+			ax, ay := v.forceField.Accel(v.volumePoints[i].x, v.volumePoints[i].y, float64(v.tick))
+			v.volumePoints[i].xVelocity += ax
+			v.volumePoints[i].yVelocity += ay
+		}
+
 		v.volumePoints[i].x += v.volumePoints[i].xVelocity // Move in x direction
 		v.volumePoints[i].y += v.volumePoints[i].yVelocity // Move in y direction
 
+		// In the fluid, points drift with the flow as their own speed dies away
+		if v.fluidOn {
+			dx, dy := v.flow(v.volumePoints[i].x, v.volumePoints[i].y)
+			v.volumePoints[i].x += dx
+			v.volumePoints[i].y += dy
+			v.volumePoints[i].xVelocity *= particleDrag
+			v.volumePoints[i].yVelocity *= particleDrag
+		}
+
+		v.volumePoints[i].sparkle *= sparkleDecay
+		if sparkle > 0.8 && v.rng.Float64() < sparkle*0.2 {
+			v.volumePoints[i].sparkle = sparkle
+		}
+
 		if v.volumePoints[i].fadeIn {
 			v.volumePoints[i].alpha += 0.05 // Gradually fade in
 			if v.volumePoints[i].alpha >= 1.0 {
 				v.volumePoints[i].alpha = 1.0
 				v.volumePoints[i].fadeIn = false // Switch to fade-out mode
 			}
+		} else if v.boundary != forces.Kill {
+			// Points that never leave the screen fade out instead
+			v.volumePoints[i].alpha -= pointFade
 		}
 
-		// Remove the point if it is off the screen or fully transparent
-		if v.volumePoints[i].x < 0 || v.volumePoints[i].x > float64(v.screenWidth) ||
-			v.volumePoints[i].y < 0 || v.volumePoints[i].y > float64(v.screenHeight) ||
-			v.volumePoints[i].alpha <= 0 {
+		// Remove the point if it has left the screen or is fully transparent
+		p := &v.volumePoints[i]
+		var inside bool
+		p.x, p.y, p.xVelocity, p.yVelocity, inside = v.boundary.Apply(p.x, p.y, p.xVelocity, p.yVelocity, float64(v.screenWidth), float64(v.screenHeight))
+		if !inside || p.alpha <= 0 {
 			v.volumePoints = append(v.volumePoints[:i], v.volumePoints[i+1:]...)
 			i--
 		}
@@ -301,9 +437,56 @@ func (v *audioVisualizer) Update() error {
 	return nil
 }
 
-// Draw renders both visualizations: waveform and radiating points.
+// applyHarmonicColor sets the colour scheme to the hue of the current chord
+// root, falling back to the key tonic between chords.
+func (v *audioVisualizer) applyHarmonicColor() {
+	pitchClass := -1
+	if v.frame.Chord.Confidence > 0 {
+		pitchClass = v.frame.Chord.Root
+	} else if v.frame.Key.Confidence > 0 {
+		pitchClass = v.frame.Key.Tonic
+	}
+	if pitchClass < 0 {
+		return
+	}
+
+	clr := utils.HSVToRGB(analysis.PitchClassHue(pitchClass, v.fifthsOrder), 1, 1)
+	v.colorScheme = colorSceme{red: int(clr.R), green: int(clr.G), blue: int(clr.B)}
+}
+
+// bandLevel returns the normalized level of the named band between 0 and 1.
+func (v *audioVisualizer) bandLevel(name string) float64 {
+	return math.Min(1, v.frame.Band(name))
+}
+
+// Draw renders the scene, blending in the next one during a transition, with
+// the overlays and HUD on top.
 func (v *audioVisualizer) Draw(screen *ebiten.Image) {
+	if v.transition != nil && !v.transition.Morph {
+		v.drawTransition(screen)
+	} else {
+		v.drawScene(screen)
+	}
+
+	v.drawOverlays(screen)
+
+	// The control surface shows the HUD instead, keeping the output clean
+	if v.control != nil {
+		v.publishThumbnail(screen)
+		return
+	}
+
+	// Draw text overlay
+	if v.showText {
+		v.drawHUD(screen)
+	}
+}
+
+// drawScene renders both visualizations: waveform and radiating points.
+func (v *audioVisualizer) drawScene(screen *ebiten.Image) {
 	screen.Fill(color.Black) // Clear the screen
+	// Drawing may happen any number of times a tick, so its randomness comes from the tick
+	v.drawRng.Seed(v.seed ^ v.tick)
 
 	// Calculate dominant frequency
 	dominantFrequency := utils.CalculateDominantFrequency(v.samples, 44100)
@@ -311,6 +494,11 @@ func (v *audioVisualizer) Draw(screen *ebiten.Image) {
 	clr := color.RGBA{R: uint8(math.Min(0, float64(v.colorScheme.red)-255*dominantFrequency/10)),
 		G: uint8(v.colorScheme.green),
 		B: uint8(math.Min(float64(v.colorScheme.blue), float64(v.colorScheme.blue)*dominantFrequency/10)), A: uint8(255 * v.volume)}
+	strokeScale := float32(0.5 + v.bandLevel(v.strokeBand))
+
+	if v.fluidOn {
+		v.drawFluid(screen)
+	}
 
 	if v.waveForm == "smooth" {
 		vertices := waveforms.SmoothWaveform(v.samples, v.screenWidth, v.screenHeight, v.waveOffset)
@@ -318,47 +506,53 @@ func (v *audioVisualizer) Draw(screen *ebiten.Image) {
 			for i := 0; i < len(vertices)-1; i++ {
 				x1, y1 := vertices[i].DstX, vertices[i].DstY
 				x2, y2 := vertices[i+1].DstX, vertices[i+1].DstY
-				vector.StrokeLine(screen, x1, y1, x2, y2, 3, clr, false)
+				vector.StrokeLine(screen, x1, y1, x2, y2, 3*strokeScale, clr, false)
 			}
 		}
 	} else if v.waveForm == "ferroliquid" {
 
 		// Draw Ferroliquid waveform visualizer
-		vertices := waveforms.FerroliquidWaveform(v.samples, v.screenWidth, v.screenHeight, v.waveOffset, v.volume*100, 0.5, 0.005)
+		vertices := waveforms.FerroliquidWaveform(v.samples, v.screenWidth, v.screenHeight, v.waveOffset, v.volume*100, 0.5, 0.005, v.drawRng)
 		if len(vertices) > 1 {
 			for i := 0; i < len(vertices)-1; i++ {
 				x1, y1 := vertices[i].DstX, vertices[i].DstY
 				x2, y2 := vertices[i+1].DstX, vertices[i+1].DstY
-				vector.StrokeLine(screen, x1, y1, x2, y2, 2, clr, false)
+				vector.StrokeLine(screen, x1, y1, x2, y2, 2*strokeScale, clr, false)
 			}
 		}
 		// Connect last and first points for a closed loop
 		if len(vertices) > 2 {
 			x1, y1 := vertices[len(vertices)-1].DstX, vertices[len(vertices)-1].DstY
 			x2, y2 := vertices[0].DstX, vertices[0].DstY
-			vector.StrokeLine(screen, x1, y1, x2, y2, 2, clr, false)
+			vector.StrokeLine(screen, x1, y1, x2, y2, 2*strokeScale, clr, false)
 		}
 	} else if v.waveForm == "bezier" {
-		vertices := waveforms.BezierWaveform(v.samples, v.screenWidth, v.screenHeight, v.waveOffset, v.volume*100)
-		if len(vertices) > 1 {
-			for i := 0; i < len(vertices)-2; i += 3 {
-				x1, y1 := vertices[i].DstX, vertices[i].DstY
-				x2, y2 := vertices[i+1].DstX, vertices[i+1].DstY
-				x3, y3 := vertices[i+2].DstX, vertices[i+2].DstY
-				vector.StrokeLine(screen, x1, y1, x2, y2, 2, clr, false)
-				vector.StrokeLine(screen, x2, y2, x3, y3, 2, clr, false)
-			}
+		path := closedCurve(waveforms.BezierWaveform(v.samples, v.screenWidth, v.screenHeight, v.waveOffset, v.volume*100, v.drawRng))
+		if v.fillWaveform {
+			fill := clr
+			fill.A /= 3
+			v.pathVertices, v.pathIndices = fillPath(screen, path, fill, true, v.pathVertices, v.pathIndices)
 		}
+		width := float32(1.5+math.Min(v.volume, 3)) * strokeScale
+		v.pathVertices, v.pathIndices = strokePath(screen, path, width, clr, true, v.pathVertices, v.pathIndices)
+	} else if v.waveForm == "blob" {
+		v.drawBlob(screen)
+	} else if v.waveForm == "ridges" {
+		v.drawTerrain(screen, waveforms.Ridges, strokeScale)
+	} else if v.waveForm == "terrain" {
+		v.drawTerrain(screen, waveforms.Wireframe, strokeScale)
 	} else {
 		fmt.Println("No Waveform")
 	}
 
+	v.drawFlock(screen)
+
 	// Draw radiating points visualizer
 	for _, p := range v.volumePoints {
 		clr := color.RGBA{
-			R: uint8(float64(v.colorScheme.red) * p.volume),
-			G: uint8(v.colorScheme.green),
-			B: uint8(float64(v.colorScheme.blue) * (1 - p.volume)),
+			R: sparkleChannel(float64(v.colorScheme.red)*p.volume, p.sparkle),
+			G: sparkleChannel(float64(v.colorScheme.green), p.sparkle),
+			B: sparkleChannel(float64(v.colorScheme.blue)*(1-p.volume), p.sparkle),
 			A: uint8(255 * p.volume * p.alpha),
 		}
 		for dx := -pointSize / 2; dx <= pointSize/2; dx++ {
@@ -367,21 +561,82 @@ func (v *audioVisualizer) Draw(screen *ebiten.Image) {
 			}
 		}
 	}
+}
 
-	// Draw text overlay
-	if v.showText {
-		textFace := basicfont.Face7x13
-		text.Draw(screen, fmt.Sprintf("Connected Device: %s", v.connectedDevice), textFace, 10, 20, color.RGBA{R: 128, G: 128, B: 128, A: 10})
-		text.Draw(screen, fmt.Sprintf("Volume: %.2f", float64(v.maxPoints)), textFace, 10, 50, color.RGBA{R: 128, G: 128, B: 128, A: 10})
-		text.Draw(screen, fmt.Sprintf("Frequency: %.2f", float64(v.frequency)), textFace, 10, 70, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+// drawHUD draws the analysis, device status and key help over the output.
+func (v *audioVisualizer) drawHUD(screen *ebiten.Image) {
+	textFace := basicfont.Face7x13
+	text.Draw(screen, fmt.Sprintf("Connected Device: %s", v.deviceLabel()), textFace, 10, 20, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("FPS: %.0f   TPS: %.0f   VSync: %t", ebiten.ActualFPS(), ebiten.ActualTPS(), ebiten.IsVsyncEnabled()), textFace, v.screenWidth-250, 20, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("Volume: %.2f", float64(v.maxPoints)), textFace, 10, 50, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("Frequency: %.2f", float64(v.frequency)), textFace, 10, 70, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	if pitch := v.frame.Pitch; pitch.Voiced() {
+		text.Draw(screen, fmt.Sprintf("Pitch: %s (%.1f Hz, %.0f%%)", pitch.Note, pitch.Frequency, pitch.Confidence*100), textFace, 200, 70, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	} else {
+		text.Draw(screen, "Pitch: -", textFace, 200, 70, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+
+	text.Draw(screen, fmt.Sprintf("Key: %s   Chord: %s", v.frame.Key, v.frame.Chord), textFace, 10, 85, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("Centroid: %.0f Hz   Flatness: %.2f   Flux: %.2f", v.frame.Features.Centroid, v.frame.Features.Flatness, v.frame.Normalized.Flux), textFace, 200, 50, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+
+	text.Draw(screen, fmt.Sprintf("R: %d", v.colorScheme.red), textFace, 10, 100, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("G: %d", v.colorScheme.green), textFace, 10, 120, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	text.Draw(screen, fmt.Sprintf("B: %d", v.colorScheme.blue), textFace, 10, 140, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+
+	// Band meters
+	for i, band := range v.analyzer.Bands() {
+		y := 170 + i*20
+		text.Draw(screen, band.Name, textFace, 10, y, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+		vector.DrawFilledRect(screen, 80, float32(y-9), float32(100*v.bandLevel(band.Name)), 9, color.RGBA{R: 128, G: 128, B: 128, A: 10}, false)
+	}
+
+	if v.currentPreset >= 0 {
+		text.Draw(screen, fmt.Sprintf("Preset: %s", v.presets[v.currentPreset].Name), textFace, 10, 35, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+	if v.pending.Index >= 0 {
+		text.Draw(screen, fmt.Sprintf("Next: %s (on the beat)", v.presets[v.pending.Index].Name), textFace, 450, 35, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+	if v.setlist != nil {
+		s := v.setlist
+		elapsed := time.Duration(s.Elapsed(v.tick) * float64(time.Second))
+		text.Draw(screen, fmt.Sprintf("Track %d/%d: %s (%s)", s.Current()+1, len(s.Tracks), s.Tracks[max(0, s.Current())].Name, elapsed.Truncate(time.Second)), textFace, 200, 35, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+	for i, line := range v.helpLines() {
+		text.Draw(screen, line, textFace, 10, v.screenHeight-10-20*i, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+	if v.pilot.On {
+		status := "Autopilot: on"
+		if v.pilot.Event != autopilot.None {
+			status += fmt.Sprintf(" (%s %.0fs ago)", v.pilot.Event, float64(v.tick-v.pilot.EventAt)/v.tps)
+		}
+		text.Draw(screen, status, textFace, v.screenWidth-250, 35, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	}
+}
 
-		text.Draw(screen, fmt.Sprintf("R: %d", v.colorScheme.red), textFace, 10, 100, color.RGBA{R: 128, G: 128, B: 128, A: 10})
-		text.Draw(screen, fmt.Sprintf("G: %d", v.colorScheme.green), textFace, 10, 120, color.RGBA{R: 128, G: 128, B: 128, A: 10})
-		text.Draw(screen, fmt.Sprintf("B: %d", v.colorScheme.blue), textFace, 10, 140, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+// deviceLabel describes the audio input for the overlay.
+func (v *audioVisualizer) deviceLabel() string {
+	if v.devices == nil {
+		return v.connectedDevice
+	}
 
-		text.Draw(screen, fmt.Sprint("Waveforms: 0 (None),   1 (Smooth)"), textFace, 10, v.screenHeight-30, color.RGBA{R: 128, G: 128, B: 128, A: 10})
-		text.Draw(screen, fmt.Sprint("Patterns:  5 (Radial), 6 (Spiral), 7 (Slinky) 8 (Spikes)"), textFace, 10, v.screenHeight-10, color.RGBA{R: 128, G: 128, B: 128, A: 10})
+	status := v.devices.Status()
+	name := status.Device.Name
+	if name == "" {
+		name = v.connectedDevice
+	}
+	switch status.State {
+	case audio.DeviceFailed, audio.DeviceReconnecting:
+		wait := max(0, time.Until(status.RetryAt)).Round(100 * time.Millisecond)
+		return fmt.Sprintf("%s (%s, retry in %s)", name, status.State, wait)
+	case audio.DeviceAbsent:
+		return "Waiting for " + v.awaiting + "..."
 	}
+	return fmt.Sprintf("%s (%s)", name, status.State)
+}
+
+// sparkleChannel lifts a colour channel towards white by the sparkle amount.
+func sparkleChannel(value, sparkle float64) uint8 {
+	return uint8(math.Min(255, value+(255-value)*sparkle))
 }
 
 func (v *audioVisualizer) Layout(outsideWidth, outsideHeight int) (int, int) {
@@ -390,164 +645,279 @@ func (v *audioVisualizer) Layout(outsideWidth, outsideHeight int) (int, int) {
 	return outsideWidth, outsideHeight
 }
 
-func RunMezmer() error {
+// Options configures a Mezmer run.
+type Options struct {
+	Input    string        // "device" to capture from an OP-XY/OP-Z, "system" for system playback, "generator", "replay", "udp", "rtp", "-" for stdin or a pipe path
+	Signal   string        // Signal played by the generator input, see audio.ParseSignal
+	Seed     int64         // Seed for every random choice; zero picks one from the clock
+	Presets  string        // Path to a JSON preset file, optional
+	Overlays string        // Path to a JSON overlay file, optional
+	Keymap   string        // Path to a JSON file rebinding key actions, optional
+	Setlist  string        // Path to a JSON setlist, optional
+	MIDI     string        // Raw MIDI device whose program changes pick setlist tracks, optional
+	Record   string        // Path to record the session to, optional
+	Session  string        // Session file played by the replay input
+	Render   string        // Directory to render a replayed session into as PNG frames, optional
+	Width    int           // Width of rendered frames
+	Height   int           // Height of rendered frames
+	Listen   string        // Address the udp and rtp inputs listen on
+	Format   string        // Sample format of streamed input, see audio.DecodePCM
+	Channels int           // Channels of streamed input
+	Rate     int           // Sample rate of streamed input
+	Latency  time.Duration // Audio buffered by the network and stream inputs
+
+	Fullscreen *bool // Start in fullscreen or not, nil to reuse the last run's state
+	Monitor    int   // Monitor to open on, 0 for the primary; negative reuses the last one
+	HideCursor bool  // Hide the mouse cursor over the window
+	Projector  bool  // Borderless, always-on-top window covering the monitor
+	VSync      bool  // Sync frames to the display refresh rate
+	TPS        int   // Updates per second, 0 for the default of 60; replays use the recorded rate
+
+	Control      string // Address to serve the performer's control surface on, optional
+	ControlToken string // Token the control surface asks for, needed unless it is only served on loopback
+
+	Autopilot        bool          // Start with the autopilot changing scenes
+	Dwell            time.Duration // Time the autopilot keeps a scene at least, default 30s
+	AutopilotPresets string        // Comma separated presets the autopilot picks from, default all
+}
+
+// RunMezmer runs the visualiser until its window is closed or ctx is
+// cancelled, then stops its inputs and saves the session and window placement.
+func RunMezmer(ctx context.Context, opts Options) error {
 	runtime.LockOSThread()
 
 	// Create a context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	ctxAudio, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
-		fmt.Printf("Log: %s\n", message)
-	})
-	if err != nil {
-		return err
+	if opts.TPS < 0 {
+		return fmt.Errorf("invalid tick rate %d", opts.TPS)
+	}
+	if opts.TPS == 0 {
+		opts.TPS = defaultTPS
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
-	defer ctxAudio.Uninit()
-	defer ctxAudio.Free()
 
-	audioInput := make([]float64, chunkSize)
-	var (
-		deviceMutex     sync.Mutex
-		deviceAvailable bool
-		targetDevice    *malgo.DeviceInfo
-		activeDevice    *malgo.Device // Track the active audio device
-	)
+	var reader *session.Reader
+	if opts.Input == "replay" {
+		var err error
+		if reader, err = session.Open(opts.Session); err != nil {
+			return err
+		}
+		defer reader.Close()
+		seed = reader.Header.Seed
+		// Replay at the recorded rate so timed changes land on the same ticks
+		opts.TPS = defaultTPS
+		if reader.Header.TPS != 0 {
+			opts.TPS = reader.Header.TPS
+		}
+	}
+	fmt.Printf("Random seed: %d\n", seed)
 
 	// Initialize the visualizer
 	initialWidth, initialHeight := 1280, 720
-	visualizer := newAudioVisualizer(chunkSize, initialWidth, initialHeight)
-
-	errChan := make(chan error)
-
-	// Goroutine to check for the OP-XY/OP-Z device
-	go func(ctx context.Context) {
-		for {
-			select {
-			case <-ctx.Done():
-				fmt.Println("Device discovery goroutine shutting down...")
-				return
-			default:
-				devices, err := ctxAudio.Devices(malgo.Capture)
-				if err != nil {
-					log.Printf("Error listing devices: %v", err)
-					time.Sleep(1 * time.Second)
-					continue
-				}
-
-				deviceMutex.Lock()
-				foundDevice := false
-				for _, device := range devices {
-					if device.Name() == "OP-XY" || device.Name() == "OP-Z" {
-						if targetDevice == nil || targetDevice.ID.Pointer() != device.ID.Pointer() {
-							targetDevice = &device
-							deviceAvailable = true
-							fmt.Printf("Found device: %s\n", device.Name())
-							visualizer.connectedDevice = device.Name()
-						}
-						foundDevice = true
-						break
-					}
-				}
-
-				if !foundDevice {
-					if deviceAvailable {
-						fmt.Println("Device disconnected.")
-						deviceAvailable = false
-						targetDevice = nil
-						visualizer.connectedDevice = "No Device"
-
-						if activeDevice != nil {
-							fmt.Println("Stopping active audio device...")
-							activeDevice.Stop()
-							activeDevice.Uninit()
-							activeDevice = nil
-						}
-					}
-				}
-				deviceMutex.Unlock()
-
-				if !deviceAvailable {
-					fmt.Println("Waiting for the OP-XY/Z device...")
-				}
-				time.Sleep(1 * time.Second)
+	visualizer := newAudioVisualizer(chunkSize, initialWidth, initialHeight, seed, float64(opts.TPS))
+	visualizer.input = audio.NewRing(sampleRate)
+	visualizer.done = ctx.Done()
+
+	if opts.Presets != "" {
+		presets, err := loadPresets(opts.Presets)
+		if err != nil {
+			return err
+		}
+		visualizer.presets = presets
+	}
+	if opts.Keymap != "" {
+		if err := visualizer.loadKeymap(opts.Keymap); err != nil {
+			return err
+		}
+	}
+	visualizer.pilot.On = opts.Autopilot
+	if opts.Dwell > 0 {
+		visualizer.pilot.Dwell = opts.Dwell.Seconds()
+	}
+	if err := visualizer.pilot.Whitelist(opts.AutopilotPresets, presetNames(visualizer.presets)); err != nil {
+		return err
+	}
+	if opts.Overlays != "" {
+		overlays, err := overlay.Load(opts.Overlays, visualizer.tps)
+		if err != nil {
+			return err
+		}
+		visualizer.overlays = overlays
+	}
+	if opts.Setlist != "" {
+		overlays := make([]string, len(visualizer.overlays))
+		for i, layer := range visualizer.overlays {
+			overlays[i] = layer.Name
+		}
+		list, err := setlist.Load(opts.Setlist, visualizer.tps, presetNames(visualizer.presets), overlays)
+		if err != nil {
+			return err
+		}
+		visualizer.setlist = list
+	}
+	if opts.MIDI != "" {
+		if visualizer.setlist == nil {
+			return fmt.Errorf("MIDI program changes need a setlist")
+		}
+		visualizer.programs = make(chan int, 16)
+		if err := readPrograms(ctx, opts.MIDI, visualizer.programs); err != nil {
+			return err
+		}
+	}
+
+	switch opts.Input {
+	case "", "device", "system":
+		stop, err := startDeviceCapture(ctx, visualizer, opts.Input == "system")
+		if err != nil {
+			return err
+		}
+		defer stop()
+	case "generator":
+		voices, err := audio.ParseSignal(opts.Signal)
+		if err != nil {
+			return err
+		}
+		generator := audio.NewGenerator(voices, sampleRate, seed)
+		go generator.Run(ctx, visualizer.input)
+		visualizer.connectedDevice = "Generator (" + opts.Signal + ")"
+	case "udp", "rtp":
+		format := opts.Format
+		if format == "" {
+			format = audio.S16LE
+			if opts.Input == "rtp" {
+				format = audio.L16
 			}
 		}
-	}(ctx)
-
-	// Goroutine to handle audio capture
-	go func(ctx context.Context) {
-		defer close(errChan)
-
-		for {
-			select {
-			case <-ctx.Done():
-				fmt.Println("Audio capture goroutine shutting down...")
-				if activeDevice != nil {
-					activeDevice.Stop()
-					activeDevice.Uninit()
-				}
-				return
-			default:
-				deviceMutex.Lock()
-				if deviceAvailable && targetDevice != nil && activeDevice == nil {
-					deviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
-					deviceConfig.Capture.Format = malgo.FormatS16
-					deviceConfig.Capture.Channels = 1
-					deviceConfig.SampleRate = 44100
-					deviceConfig.Capture.DeviceID = targetDevice.ID.Pointer()
-
-					deviceCallbacks := malgo.DeviceCallbacks{
-						Data: func(_, inputSamples []byte, frameCount uint32) {
-							for i := 0; i < int(frameCount) && i < len(audioInput); i++ {
-								sample := int16(inputSamples[2*i]) | int16(inputSamples[2*i+1])<<8
-								audioInput[i] = float64(sample) / 32768.0 // Convert to float64
-							}
-						},
-					}
-
-					device, err := malgo.InitDevice(ctxAudio.Context, deviceConfig, deviceCallbacks)
-					if err != nil {
-						log.Printf("Failed to initialize device: %v", err)
-						deviceMutex.Unlock()
-						continue
-					}
-
-					if err := device.Start(); err != nil {
-						log.Printf("Failed to start device: %v", err)
-						device.Uninit()
-						deviceMutex.Unlock()
-						continue
-					}
-
-					fmt.Println("Audio device started.")
-					activeDevice = device
-				}
-
-				if !deviceAvailable && activeDevice != nil {
-					fmt.Println("Stopping active audio device due to disconnection...")
-					activeDevice.Stop()
-					activeDevice.Uninit()
-					activeDevice = nil
-				}
-				deviceMutex.Unlock()
-
-				if activeDevice != nil {
-					copy(visualizer.currentChunk, audioInput)
-				}
-				time.Sleep(100 * time.Millisecond) // Wait for device to be available
+		receiver, err := audio.ListenNetwork(audio.NetworkConfig{
+			Protocol:   opts.Input,
+			Address:    opts.Listen,
+			Format:     format,
+			Channels:   opts.Channels,
+			SampleRate: opts.Rate,
+			Latency:    opts.Latency,
+		}, sampleRate)
+		if err != nil {
+			return err
+		}
+		go receiver.Run(ctx, visualizer.input)
+		visualizer.connectedDevice = fmt.Sprintf("%s %s (%s)", strings.ToUpper(opts.Input), receiver.Addr(), format)
+	case "replay":
+		visualizer.replay = &replay{reader: reader, held: keymap.Held{}}
+		visualizer.connectedDevice = "Replay (" + opts.Session + ")"
+	default:
+		// Anything else is stdin ("-") or a path to a named pipe or raw PCM file
+		if opts.Input != "-" {
+			if _, err := os.Stat(opts.Input); err != nil {
+				return fmt.Errorf("unknown input %q: %w", opts.Input, err)
 			}
 		}
-	}(ctx)
+		format := opts.Format
+		if format == "" {
+			format = audio.S16LE
+		}
+		stream, err := audio.NewPCMReader(opts.Input, format, opts.Channels, opts.Rate, sampleRate, opts.Latency)
+		if err != nil {
+			return err
+		}
+		go stream.Run(ctx, visualizer.input)
+		name := opts.Input
+		if name == "-" {
+			name = "stdin"
+		}
+		visualizer.connectedDevice = fmt.Sprintf("PCM %s (%s)", name, format)
+	}
+
+	if opts.Record != "" {
+		recorder, err := session.Create(opts.Record, session.Header{SampleRate: sampleRate, Seed: seed, TPS: opts.TPS})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			visualizer.input.Record(nil)
+			if err := recorder.Close(); err != nil {
+				log.Printf("Failed to save session: %v", err)
+			}
+		}()
+		visualizer.recorder = recorder
+		visualizer.input.Record(recorder)
+	}
+
+	if opts.Control != "" {
+		server, err := control.Listen(opts.Control, opts.ControlToken)
+		if err != nil {
+			return err
+		}
+		go server.Serve(ctx)
+		visualizer.control = server
+		fmt.Printf("Control surface at %s\n", server.URL())
+	}
+
+	if opts.Render != "" {
+		if visualizer.replay == nil {
+			return fmt.Errorf("rendering frames needs the replay input")
+		}
+		return renderOffline(visualizer, opts.Render, opts.Width, opts.Height)
+	}
 
 	// Run the Ebiten visualizer
-	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
-	ebiten.SetWindowSize(initialWidth, initialHeight)
-	ebiten.SetWindowTitle("Mezmer")
+	setupWindow(opts, initialWidth, initialHeight)
+	if !opts.Projector {
+		visualizer.placement = &windowPlacement{}
+		ebiten.SetWindowClosingHandled(true)
+	}
 	if err := ebiten.RunGame(visualizer); err != nil {
 		cancel() // Cancel context to stop goroutines
 		return err
 	}
 
+	if visualizer.placement != nil && visualizer.placement.Width > 0 {
+		if err := savePlacement(*visualizer.placement); err != nil {
+			log.Printf("Failed to save window placement: %v", err)
+		}
+	}
 	return nil
 }
+
+// startDeviceCapture keeps an OP-XY/OP-Z, or the system output when system
+// is set, streaming into the visualizer's input, reopening it when it is
+// unplugged or fails. The returned function stops capture and releases the
+// audio context.
+func startDeviceCapture(ctx context.Context, visualizer *audioVisualizer, system bool) (func(), error) {
+	ctxAudio, err := malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
+		fmt.Printf("Log: %s\n", message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if system {
+		// The system enumerator lists only playback captures, so take the first
+		enumerator := audio.NewMalgoSystemEnumerator(ctxAudio, sampleRate)
+		visualizer.devices = audio.NewDeviceManager(enumerator, func(string) bool { return true }, visualizer.input)
+		visualizer.awaiting = "a monitor/loopback source"
+	} else {
+		enumerator := audio.NewMalgoEnumerator(ctxAudio, sampleRate)
+		visualizer.devices = audio.NewDeviceManager(enumerator, audio.MatchNames("OP-XY", "OP-Z"), visualizer.input)
+		visualizer.awaiting = "the OP-XY/Z device"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		visualizer.devices.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		ctxAudio.Uninit()
+		ctxAudio.Free()
+	}, nil
+}
@@ -0,0 +1,146 @@
+package visualiser
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const placementFile = "window.json" // Saved under the user config directory
+
+// windowPlacement is where the window was when Mezmer last exited.
+type windowPlacement struct {
+	Monitor    string `json:"monitor"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Fullscreen bool   `json:"fullscreen"`
+}
+
+func placementPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mezmer", placementFile), nil
+}
+
+// loadPlacement returns the saved placement, or false if there is none.
+func loadPlacement() (windowPlacement, bool) {
+	var placement windowPlacement
+	path, err := placementPath()
+	if err != nil {
+		return placement, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to read window placement: %v", err)
+		}
+		return placement, false
+	}
+	if err := json.Unmarshal(data, &placement); err != nil {
+		log.Printf("Failed to parse window placement %s: %v", path, err)
+		return placement, false
+	}
+	return placement, placement.Width > 0 && placement.Height > 0
+}
+
+func savePlacement(placement windowPlacement) error {
+	path, err := placementPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(placement, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// findMonitor returns the monitor at index, counting from 0 for the primary,
+// or the one called name when index is negative. It returns nil when
+// neither matches.
+func findMonitor(index int, name string) *ebiten.MonitorType {
+	monitors := ebiten.AppendMonitors(nil)
+	if index >= 0 {
+		if index < len(monitors) {
+			return monitors[index]
+		}
+		log.Printf("No monitor %d, %d connected", index, len(monitors))
+		return nil
+	}
+	for _, monitor := range monitors {
+		if name != "" && monitor.Name() == name {
+			return monitor
+		}
+	}
+	return nil
+}
+
+// setupWindow applies the window options before the game starts. Anything
+// the options leave open is restored from the last run.
+func setupWindow(opts Options, width, height int) {
+	saved, restore := loadPlacement()
+
+	monitor := findMonitor(opts.Monitor, saved.Monitor)
+	if monitor != nil {
+		ebiten.SetMonitor(monitor)
+	}
+
+	ebiten.SetWindowTitle("Mezmer")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetVsyncEnabled(opts.VSync)
+	ebiten.SetTPS(opts.TPS)
+	if opts.HideCursor {
+		ebiten.SetCursorMode(ebiten.CursorModeHidden)
+	}
+
+	switch {
+	case opts.Projector:
+		// A borderless window covering the monitor, kept above everything else
+		// so a desktop notification never lands on the projector
+		if monitor == nil {
+			monitor = ebiten.Monitor()
+		}
+		w, h := monitor.Size()
+		ebiten.SetWindowDecorated(false)
+		ebiten.SetWindowFloating(true)
+		ebiten.SetWindowSize(w, h)
+		ebiten.SetWindowPosition(0, 0)
+	case restore && (opts.Monitor < 0 || monitor == nil || monitor.Name() == saved.Monitor):
+		ebiten.SetWindowSize(saved.Width, saved.Height)
+		ebiten.SetWindowPosition(saved.X, saved.Y)
+		fullscreen := saved.Fullscreen
+		if opts.Fullscreen != nil {
+			fullscreen = *opts.Fullscreen
+		}
+		ebiten.SetFullscreen(fullscreen)
+	default:
+		ebiten.SetWindowSize(width, height)
+		ebiten.SetFullscreen(opts.Fullscreen != nil && *opts.Fullscreen)
+	}
+}
+
+// trackWindow records where the window is, so it can be saved on exit.
+// Projector windows always cover their monitor, so they are not tracked.
+func (v *audioVisualizer) trackWindow() {
+	if v.placement == nil || ebiten.IsWindowMinimized() {
+		return
+	}
+	if monitor := ebiten.Monitor(); monitor != nil {
+		v.placement.Monitor = monitor.Name()
+	}
+	v.placement.Fullscreen = ebiten.IsFullscreen()
+	v.placement.Width, v.placement.Height = ebiten.WindowSize()
+	v.placement.X, v.placement.Y = ebiten.WindowPosition()
+}
@@ -0,0 +1,102 @@
+package waveforms
+
+import (
+	"image/color"
+	"math"
+)
+
+const (
+	blobSegments  = 128 // Points around the blob's edge
+	blobSmoothing = 2   // Neighbours averaged on each side of an edge point
+)
+
+// BlobStyle colours a blob. The fill blends from Inner at the centre to
+// Outer at the edge, and a band of Rim light fades in towards the edge.
+type BlobStyle struct {
+	Inner    color.RGBA
+	Outer    color.RGBA
+	Rim      color.RGBA
+	RimWidth float64 // Width of the rim light as a fraction of the radius
+}
+
+// BlobWaveform appends a filled polar blob to vertices and indices, for
+// drawing with DrawTriangles from a source that is white at (1, 1). The
+// edge sits at radius, pushed out and in by samples scaled by amplitude.
+// The fill is a fan around the centre, so the gradient comes from
+// interpolating the vertex colours.
+func BlobWaveform(vertices []Vertex, indices []uint16, samples []float64, centerX, centerY, radius, amplitude, offset float64, style BlobStyle) ([]Vertex, []uint16) {
+	if len(samples) == 0 || radius <= 0 {
+		return vertices, indices
+	}
+
+	// Average the samples into one level per segment, then smooth around the
+	// loop so the edge wobbles rather than jitters
+	levels := make([]float64, blobSegments)
+	for i := range levels {
+		start, end := i*len(samples)/blobSegments, (i+1)*len(samples)/blobSegments
+		if end == start {
+			end = start + 1
+		}
+		var sum float64
+		for _, sample := range samples[start:min(end, len(samples))] {
+			sum += sample
+		}
+		levels[i] = sum / float64(end-start)
+	}
+	radii := make([]float64, blobSegments)
+	for i := range radii {
+		var sum float64
+		for j := -blobSmoothing; j <= blobSmoothing; j++ {
+			sum += levels[(i+j+blobSegments)%blobSegments]
+		}
+		level := sum / (2*blobSmoothing + 1)
+		radii[i] = radius * math.Max(0.1, 1+amplitude*level)
+	}
+
+	// Fan from the centre to the edge
+	base := uint16(len(vertices))
+	vertices = append(vertices, vertex(centerX, centerY, style.Inner))
+	for i, r := range radii {
+		angle := (float64(i)/blobSegments + offset) * 2 * math.Pi
+		vertices = append(vertices, vertex(centerX+r*math.Cos(angle), centerY+r*math.Sin(angle), style.Outer))
+	}
+	for i := 0; i < blobSegments; i++ {
+		next := (i + 1) % blobSegments
+		indices = append(indices, base, base+1+uint16(i), base+1+uint16(next))
+	}
+
+	if style.RimWidth <= 0 {
+		return vertices, indices
+	}
+
+	// Rim light: a strip just inside the edge, clear on its inner side
+	clear := style.Rim
+	clear.A = 0
+	base = uint16(len(vertices))
+	for i, r := range radii {
+		angle := (float64(i)/blobSegments + offset) * 2 * math.Pi
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		inner := r * (1 - style.RimWidth)
+		vertices = append(vertices,
+			vertex(centerX+inner*cos, centerY+inner*sin, clear),
+			vertex(centerX+r*cos, centerY+r*sin, style.Rim),
+		)
+	}
+	for i := 0; i < blobSegments; i++ {
+		next := (i + 1) % blobSegments
+		a, b := base+2*uint16(i), base+2*uint16(next)
+		indices = append(indices, a, a+1, b+1, a, b+1, b)
+	}
+	return vertices, indices
+}
+
+func vertex(x, y float64, clr color.RGBA) Vertex {
+	return Vertex{
+		DstX: float32(x), DstY: float32(y),
+		SrcX: 1, SrcY: 1,
+		ColorR: float32(clr.R) / 0xff,
+		ColorG: float32(clr.G) / 0xff,
+		ColorB: float32(clr.B) / 0xff,
+		ColorA: float32(clr.A) / 0xff,
+	}
+}
@@ -0,0 +1,75 @@
+package waveforms
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestBlobWaveform(t *testing.T) {
+	inner := color.RGBA{R: 0xff, A: 0xff}
+	outer := color.RGBA{B: 0xff, A: 0xff}
+	rim := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}
+	tests := []struct {
+		name     string
+		samples  []float64
+		radius   float64
+		style    BlobStyle
+		vertices int
+		indices  int
+		edge     float64 // Distance of the edge from the centre
+	}{
+		{"silence", make([]float64, 512), 100, BlobStyle{Inner: inner, Outer: outer}, 1 + blobSegments, 3 * blobSegments, 100},
+		{"rim", make([]float64, 512), 100, BlobStyle{Inner: inner, Outer: outer, Rim: rim, RimWidth: 0.1}, 1 + 3*blobSegments, 9 * blobSegments, 100},
+		{"loud", constant(512, 0.5), 100, BlobStyle{}, 1 + blobSegments, 3 * blobSegments, 150},
+		{"pulled in", constant(512, -5), 100, BlobStyle{}, 1 + blobSegments, 3 * blobSegments, 10}, // Never below a tenth
+		{"fewer samples than segments", constant(16, 0.5), 100, BlobStyle{}, 1 + blobSegments, 3 * blobSegments, 150},
+		{"no samples", nil, 100, BlobStyle{}, 0, 0, 0},
+		{"no radius", constant(512, 0.5), 0, BlobStyle{}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		vertices, indices := BlobWaveform(nil, nil, tt.samples, 200, 100, tt.radius, 1, 0, tt.style)
+		if len(vertices) != tt.vertices || len(indices) != tt.indices {
+			t.Errorf("%s: %d vertices and %d indices, want %d and %d", tt.name, len(vertices), len(indices), tt.vertices, tt.indices)
+			continue
+		}
+		if len(vertices) == 0 {
+			continue
+		}
+
+		if centre := vertices[0]; centre.DstX != 200 || centre.DstY != 100 || centre.ColorR != float32(tt.style.Inner.R)/0xff {
+			t.Errorf("%s: the fan starts at %v, want the centre in the inner colour", tt.name, centre)
+		}
+		for _, edge := range vertices[1 : 1+blobSegments] {
+			distance := math.Hypot(float64(edge.DstX)-200, float64(edge.DstY)-100)
+			if math.Abs(distance-tt.edge) > 1e-3 {
+				t.Errorf("%s: an edge point is %.2f from the centre, want %.2f", tt.name, distance, tt.edge)
+				break
+			}
+		}
+	}
+}
+
+func TestBlobWaveformAppends(t *testing.T) {
+	style := BlobStyle{RimWidth: 0.1}
+	vertices, indices := BlobWaveform(nil, nil, constant(64, 0), 0, 0, 10, 1, 0, style)
+	first := len(vertices)
+	vertices, indices = BlobWaveform(vertices, indices, constant(64, 0), 0, 0, 5, 1, 0, style)
+	if len(vertices) != 2*first {
+		t.Fatalf("Two blobs have %d vertices, want %d", len(vertices), 2*first)
+	}
+	// The second blob's indices refer to its own vertices
+	for _, index := range indices[len(indices)/2:] {
+		if int(index) < first || int(index) >= len(vertices) {
+			t.Fatalf("The second blob uses vertex %d, outside %d to %d", index, first, len(vertices))
+		}
+	}
+}
+
+func constant(n int, value float64) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = value
+	}
+	return samples
+}
@@ -0,0 +1,182 @@
+package waveforms
+
+import (
+	"image/color"
+	"math"
+)
+
+const (
+	terrainWidth   = 3.0  // Width of the landscape in world units
+	terrainSpacing = 0.1  // Distance between rows in world units
+	terrainNear    = 1.0  // Distance from the camera to the front row
+	terrainHeight  = 0.5  // Height of a peak at full level
+	terrainGain    = 50.0 // Spectrum magnitude scaling before compression
+
+	scrollTolerance = 1e-9 // Rounding allowed in the scroll before a row is due
+)
+
+// TerrainStyle chooses how a Terrain is drawn.
+type TerrainStyle int
+
+const (
+	Ridges    TerrainStyle = iota // Stacked ridge lines, as on Unknown Pleasures
+	Wireframe                     // A grid of rows and columns
+)
+
+// Camera looks at a Terrain from above its front edge.
+type Camera struct {
+	Height float64 // Height above the ground in world units
+	Tilt   float64 // Downward pitch in radians
+}
+
+// Terrain keeps a history of spectra as rows of a landscape that scrolls
+// away from the camera.
+type Terrain struct {
+	columns int
+	rows    [][]float64 // Levels between 0 and 1, newest first
+	scroll  float64     // Fraction of a row the landscape has moved since the newest row
+}
+
+// NewTerrain creates a landscape columns wide and depth rows deep.
+func NewTerrain(columns, depth int) *Terrain {
+	rows := make([][]float64, depth)
+	for i := range rows {
+		rows[i] = make([]float64, columns)
+	}
+	return &Terrain{columns: columns, rows: rows}
+}
+
+// Advance scrolls the landscape by speed rows and, whenever a whole row has
+// passed, adds spectrum as the new front row. Columns cover the spectrum
+// on a logarithmic scale, so each octave gets the same width.
+func (t *Terrain) Advance(spectrum []float64, speed float64) {
+	t.scroll += speed
+	// Allow for rounding, so ten steps of 0.3 scroll three rows
+	for t.scroll >= 1-scrollTolerance {
+		t.scroll = max(0, t.scroll-1)
+
+		// Recycle the oldest row as the newest
+		row := t.rows[len(t.rows)-1]
+		copy(t.rows[1:], t.rows[:len(t.rows)-1])
+		t.rows[0] = row
+		t.fill(row, spectrum)
+	}
+}
+
+func (t *Terrain) fill(row, spectrum []float64) {
+	if len(spectrum) < 2 {
+		clear(row)
+		return
+	}
+	bins := float64(len(spectrum))
+	for i := range row {
+		// Skip the DC bin and take the loudest bin in each column's range
+		start := int(math.Pow(bins, float64(i)/float64(len(row))))
+		end := max(start+1, int(math.Pow(bins, float64(i+1)/float64(len(row)))))
+		var peak float64
+		for _, magnitude := range spectrum[start:min(end, len(spectrum))] {
+			peak = math.Max(peak, magnitude)
+		}
+		row[i] = math.Min(1, math.Log10(1+peak*terrainGain)/math.Log10(1+terrainGain))
+	}
+}
+
+// AppendMesh appends the landscape as seen by camera on a width by height
+// screen to vertices and indices, for drawing with DrawTriangles from a
+// source that is white at (1, 1). Rows are added from the back to the
+// front, each filled with background, so nearer rows hide the lines of
+// those behind them.
+func (t *Terrain) AppendMesh(vertices []Vertex, indices []uint16, width, height int, camera Camera, style TerrainStyle, line, background color.RGBA, lineWidth float64) ([]Vertex, []uint16) {
+	project := t.projection(width, height, camera)
+	points := make([][][2]float64, len(t.rows))
+	bases := make([][][2]float64, len(t.rows))
+	for r, row := range t.rows {
+		z := terrainNear + (float64(r)+t.scroll)*terrainSpacing
+		points[r] = make([][2]float64, t.columns)
+		bases[r] = make([][2]float64, t.columns)
+		for c, level := range row {
+			x := (float64(c)/float64(t.columns-1) - 0.5) * terrainWidth
+			if style == Ridges {
+				// Taper the edges so each ridge rises from a flat line
+				level *= math.Pow(math.Sin(math.Pi*float64(c)/float64(t.columns-1)), 2)
+			}
+			points[r][c] = project(x, level*terrainHeight, z)
+			bases[r][c] = project(x, 0, z)
+		}
+	}
+
+	for r := len(t.rows) - 1; r >= 0; r-- {
+		switch style {
+		case Ridges:
+			vertices, indices = appendStrip(vertices, indices, points[r], bases[r], background)
+			vertices, indices = appendPolyline(vertices, indices, points[r], lineWidth, line)
+		case Wireframe:
+			if r == 0 {
+				vertices, indices = appendPolyline(vertices, indices, points[r], lineWidth, line)
+				continue
+			}
+			vertices, indices = appendStrip(vertices, indices, points[r], points[r-1], background)
+			vertices, indices = appendPolyline(vertices, indices, points[r], lineWidth, line)
+			for c := range points[r] {
+				vertices, indices = appendSegment(vertices, indices, points[r][c], points[r-1][c], lineWidth, line)
+			}
+		}
+	}
+	return vertices, indices
+}
+
+// projection returns a perspective projection from world to screen
+// coordinates for camera, which stands above the origin looking along z.
+func (t *Terrain) projection(width, height int, camera Camera) func(x, y, z float64) [2]float64 {
+	cos, sin := math.Cos(camera.Tilt), math.Sin(camera.Tilt)
+	focal := float64(height) * 0.9
+	return func(x, y, z float64) [2]float64 {
+		y -= camera.Height
+		viewY := y*cos + z*sin
+		viewZ := math.Max(0.01, z*cos-y*sin)
+		return [2]float64{
+			float64(width)/2 + x/viewZ*focal,
+			float64(height)/2 - viewY/viewZ*focal,
+		}
+	}
+}
+
+// appendStrip fills the area between two polylines of the same length.
+func appendStrip(vertices []Vertex, indices []uint16, top, bottom [][2]float64, clr color.RGBA) ([]Vertex, []uint16) {
+	base := uint16(len(vertices))
+	for i := range top {
+		vertices = append(vertices, vertex(top[i][0], top[i][1], clr), vertex(bottom[i][0], bottom[i][1], clr))
+	}
+	for i := 0; i < len(top)-1; i++ {
+		a, b := base+2*uint16(i), base+2*uint16(i+1)
+		indices = append(indices, a, a+1, b+1, a, b+1, b)
+	}
+	return vertices, indices
+}
+
+func appendPolyline(vertices []Vertex, indices []uint16, points [][2]float64, width float64, clr color.RGBA) ([]Vertex, []uint16) {
+	for i := 0; i < len(points)-1; i++ {
+		vertices, indices = appendSegment(vertices, indices, points[i], points[i+1], width, clr)
+	}
+	return vertices, indices
+}
+
+// appendSegment adds a line from p to q as a quad width pixels wide.
+func appendSegment(vertices []Vertex, indices []uint16, p, q [2]float64, width float64, clr color.RGBA) ([]Vertex, []uint16) {
+	dx, dy := q[0]-p[0], q[1]-p[1]
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return vertices, indices
+	}
+	nx, ny := -dy/length*width/2, dx/length*width/2
+
+	base := uint16(len(vertices))
+	vertices = append(vertices,
+		vertex(p[0]+nx, p[1]+ny, clr),
+		vertex(p[0]-nx, p[1]-ny, clr),
+		vertex(q[0]-nx, q[1]-ny, clr),
+		vertex(q[0]+nx, q[1]+ny, clr),
+	)
+	indices = append(indices, base, base+1, base+2, base, base+2, base+3)
+	return vertices, indices
+}
@@ -0,0 +1,186 @@
+package waveforms
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// spectrumWith returns a spectrum of size bins, silent but for the given
+// magnitudes.
+func spectrumWith(size int, magnitudes map[int]float64) []float64 {
+	spectrum := make([]float64, size)
+	for bin, magnitude := range magnitudes {
+		spectrum[bin] = magnitude
+	}
+	return spectrum
+}
+
+func TestTerrainAdvance(t *testing.T) {
+	tests := []struct {
+		speed  float64
+		ticks  int
+		rows   int // New rows added
+		scroll float64
+	}{
+		{0.5, 1, 0, 0.5},
+		{0.5, 2, 1, 0},
+		{0.3, 10, 3, 0},
+		{0.25, 7, 1, 0.75},
+		{2.5, 1, 2, 0.5},
+		{0, 100, 0, 0},
+	}
+	for _, tt := range tests {
+		terrain := NewTerrain(4, 8)
+		loud := spectrumWith(17, map[int]float64{1: 1, 2: 1, 4: 1, 8: 1})
+		for i := 0; i < tt.ticks; i++ {
+			terrain.Advance(loud, tt.speed)
+		}
+
+		rows := 0
+		for _, row := range terrain.rows {
+			if row[0] > 0 {
+				rows++
+			}
+		}
+		if rows != tt.rows || math.Abs(terrain.scroll-tt.scroll) > 1e-9 {
+			t.Errorf("%d ticks at %g: %d rows with scroll %g, want %d rows with scroll %g",
+				tt.ticks, tt.speed, rows, terrain.scroll, tt.rows, tt.scroll)
+		}
+	}
+}
+
+func TestTerrainRecyclesRows(t *testing.T) {
+	terrain := NewTerrain(4, 3)
+	allocated := map[*float64]bool{}
+	for _, row := range terrain.rows {
+		allocated[&row[0]] = true
+	}
+
+	// Each row is stamped with its own level in the first column
+	for i := 1; i <= 5; i++ {
+		terrain.Advance(spectrumWith(17, map[int]float64{1: float64(i) / 10}), 1)
+	}
+	for r, row := range terrain.rows {
+		if !allocated[&row[0]] {
+			t.Errorf("Row %d was allocated by Advance", r)
+		}
+	}
+
+	// Newest first, and the two oldest are gone
+	for r, want := range []float64{0.5, 0.4, 0.3} {
+		if got := terrain.rows[r][0]; got != level(want) {
+			t.Errorf("Row %d has level %.3f, want %.3f from the spectrum %d rows ago", r, got, level(want), r)
+		}
+	}
+}
+
+func level(magnitude float64) float64 {
+	return math.Log10(1+magnitude*terrainGain) / math.Log10(1+terrainGain)
+}
+
+func TestTerrainFill(t *testing.T) {
+	// 17 bins across 4 columns give octaves: bins 1, 2-3, 4-7 and 8-16
+	tests := []struct {
+		name     string
+		spectrum []float64
+		want     []float64
+	}{
+		{"silence", spectrumWith(17, nil), []float64{0, 0, 0, 0}},
+		{"DC is ignored", spectrumWith(17, map[int]float64{0: 100}), []float64{0, 0, 0, 0}},
+		{"lowest bin", spectrumWith(17, map[int]float64{1: 1}), []float64{1, 0, 0, 0}},
+		{"octave", spectrumWith(17, map[int]float64{2: 0.1, 3: 0.2}), []float64{0, level(0.2), 0, 0}},
+		{"loudest bin wins", spectrumWith(17, map[int]float64{4: 0.05, 7: 0.5}), []float64{0, 0, level(0.5), 0}},
+		{"highest bin", spectrumWith(17, map[int]float64{16: 0.3}), []float64{0, 0, 0, level(0.3)}},
+		{"clipped", spectrumWith(17, map[int]float64{8: 10}), []float64{0, 0, 0, 1}},
+		{"too short", []float64{1}, []float64{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		terrain := NewTerrain(4, 1)
+		row := []float64{9, 9, 9, 9}
+		terrain.fill(row, tt.spectrum)
+		for i := range row {
+			if math.Abs(row[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: columns are %.3f, want %.3f", tt.name, row, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestTerrainProjection(t *testing.T) {
+	const width, height = 640, 360
+	tests := []struct {
+		name    string
+		camera  Camera
+		x, y, z float64
+		want    [2]float64
+	}{
+		{"eye level", Camera{Height: 0.5}, 0, 0.5, 2, [2]float64{320, 180}},
+		{"straight ahead when tilted", Camera{Height: 0.5, Tilt: 0.3}, 0, 0.5 - 2*math.Tan(0.3), 2, [2]float64{320, 180}},
+		{"right", Camera{Height: 0.5}, 1, 0.5, 2, [2]float64{320 + 0.5*0.9*height, 180}},
+		{"ground", Camera{Height: 0.5}, 0, 0, 1, [2]float64{320, 180 + 0.5*0.9*height}},
+	}
+	for _, tt := range tests {
+		terrain := NewTerrain(4, 1)
+		got := terrain.projection(width, height, tt.camera)(tt.x, tt.y, tt.z)
+		if math.Abs(got[0]-tt.want[0]) > 1e-9 || math.Abs(got[1]-tt.want[1]) > 1e-9 {
+			t.Errorf("%s: projected to %.2f, want %.2f", tt.name, got, tt.want)
+		}
+	}
+
+	// Rows further away rise towards the horizon
+	project := NewTerrain(4, 1).projection(width, height, Camera{Height: 0.5, Tilt: 0.15})
+	near, far := project(0, 0, terrainNear), project(0, 0, terrainNear+10*terrainSpacing)
+	if far[1] >= near[1] {
+		t.Errorf("The ground %g away is at y %.1f, not above the front row at %.1f", 10*terrainSpacing, far[1], near[1])
+	}
+}
+
+func TestTerrainMeshSize(t *testing.T) {
+	const columns, depth = 8, 5
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	tests := []struct {
+		style    TerrainStyle
+		vertices int
+		indices  int
+	}{
+		// Per row a filled strip and a ridge line of quads
+		{Ridges, depth * (2*columns + 4*(columns-1)), depth * (6*(columns-1) + 6*(columns-1))},
+		// The front row is just a line; the others add a strip and a quad down each column
+		{Wireframe, 4*(columns-1) + (depth-1)*(2*columns+4*(columns-1)+4*columns), 6*(columns-1) + (depth-1)*(6*(columns-1)+6*(columns-1)+6*columns)},
+	}
+	for _, tt := range tests {
+		terrain := NewTerrain(columns, depth)
+		vertices, indices := terrain.AppendMesh(nil, nil, 640, 360, Camera{Height: 0.5, Tilt: 0.15}, tt.style, white, color.RGBA{A: 0xff}, 1)
+		if len(vertices) != tt.vertices || len(indices) != tt.indices {
+			t.Errorf("Style %d: %d vertices and %d indices, want %d and %d", tt.style, len(vertices), len(indices), tt.vertices, tt.indices)
+		}
+	}
+}
+
+func TestTerrainFitsUint16Indices(t *testing.T) {
+	// The size the visualiser draws, with every column at full height
+	const columns, depth = 64, 48
+	terrain := NewTerrain(columns, depth)
+	loud := make([]float64, 512)
+	for i := range loud {
+		loud[i] = 1
+	}
+	for i := 0; i < depth; i++ {
+		terrain.Advance(loud, 1)
+	}
+
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	for _, style := range []TerrainStyle{Ridges, Wireframe} {
+		vertices, indices := terrain.AppendMesh(nil, nil, 3840, 2160, Camera{Height: 0.5, Tilt: 0.15}, style, white, color.RGBA{A: 0xff}, 2)
+		if len(vertices) > math.MaxUint16+1 {
+			t.Fatalf("Style %d needs %d vertices, more than uint16 indices can reach", style, len(vertices))
+		}
+		for _, index := range indices {
+			if int(index) >= len(vertices) {
+				t.Fatalf("Style %d has index %d past its %d vertices", style, index, len(vertices))
+			}
+		}
+	}
+}
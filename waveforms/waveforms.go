@@ -1,10 +1,10 @@
+// Package waveforms builds the geometry of the waveform visualizations. It
+// leaves drawing to the caller, so it doesn't need a display.
 package waveforms
 
 import (
 	"math"
 	"math/rand"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 const (
@@ -12,10 +12,11 @@ const (
 	smoothingFactor = 0.02
 	amplitudeFactor = 0.01 // Reduce sensitivity of amplitude changes
 	centerMoveSpeed = 0.2
+	bezierPoints    = 64 // Points the Bézier waveform's curve passes through
 )
 
-func SmoothWaveform(samples []float64, screenWidth, screenHeight int, offset float64) []ebiten.Vertex {
-	vertices := make([]ebiten.Vertex, 0, len(samples))
+func SmoothWaveform(samples []float64, screenWidth, screenHeight int, offset float64) []Vertex {
+	vertices := make([]Vertex, 0, len(samples))
 	centerY := float64(screenHeight) / 2
 	//stepX := float64(screenWidth) / float64(len(samples)+1)
 	stepX := float64(screenWidth) / 128
@@ -24,7 +25,7 @@ func SmoothWaveform(samples []float64, screenWidth, screenHeight int, offset flo
 	for i, sample := range samples {
 		x := float32(float64(i) * stepX)
 		y := float32(centerY + sample*scaleY)
-		vertices = append(vertices, ebiten.Vertex{
+		vertices = append(vertices, Vertex{
 			DstX: x, DstY: y,
 			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 0.1,
 		})
@@ -33,12 +34,12 @@ func SmoothWaveform(samples []float64, screenWidth, screenHeight int, offset flo
 	return vertices
 }
 
-func FerroliquidWaveform(samples []float64, screenWidth, screenHeight int, offset float64, radiusControl float64, smoothingFactor float64, amplitudeFactor float64) []ebiten.Vertex {
-	var previousVertices []ebiten.Vertex
-	vertices := make([]ebiten.Vertex, 0, len(samples)*2)
+func FerroliquidWaveform(samples []float64, screenWidth, screenHeight int, offset float64, radiusControl float64, smoothingFactor float64, amplitudeFactor float64, rng *rand.Rand) []Vertex {
+	var previousVertices []Vertex
+	vertices := make([]Vertex, 0, len(samples)*2)
 	centerX := float64(screenWidth) / 2
 	centerY := float64(screenHeight) / 2
-	maxRadius := radiusControl * rand.Float64()
+	maxRadius := radiusControl * rng.Float64()
 
 	// Smoothing for radii
 	smoothedRadius := make([]float64, len(samples))
@@ -51,13 +52,13 @@ func FerroliquidWaveform(samples []float64, screenWidth, screenHeight int, offse
 	}
 
 	// Generate current vertices
-	currentVertices := make([]ebiten.Vertex, len(samples))
+	currentVertices := make([]Vertex, len(samples))
 	for i := 0; i < len(samples); i++ {
 		angle := (float64(i) + offset) / float64(len(samples)) * 2 * math.Pi
 		radius := smoothedRadius[i] * (1 + amplitudeFactor*math.Sin(offset+float64(i)/10))
 		x := centerX + radius*math.Cos(angle)
 		y := centerY + radius*math.Sin(angle)
-		currentVertices[i] = ebiten.Vertex{
+		currentVertices[i] = Vertex{
 			DstX: float32(x), DstY: float32(y),
 			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
 		}
@@ -69,7 +70,7 @@ func FerroliquidWaveform(samples []float64, screenWidth, screenHeight int, offse
 		for i := 0; i < len(samples); i++ {
 			x := float32((1-interpolationFactor)*float64(previousVertices[i].DstX) + interpolationFactor*float64(currentVertices[i].DstX))
 			y := float32((1-interpolationFactor)*float64(previousVertices[i].DstY) + interpolationFactor*float64(currentVertices[i].DstY))
-			vertices = append(vertices, ebiten.Vertex{
+			vertices = append(vertices, Vertex{
 				DstX: x, DstY: y,
 				ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
 			})
@@ -83,35 +84,42 @@ func FerroliquidWaveform(samples []float64, screenWidth, screenHeight int, offse
 	return vertices
 }
 
-func BezierWaveform(samples []float64, screenWidth, screenHeight int, offset float64, radiusControl float64) []ebiten.Vertex {
-	vertices := make([]ebiten.Vertex, 0, len(samples)*2)
+// BezierWaveform returns bezierPoints polar points taken from samples, for
+// joining into a closed curve whose outline is smooth and has no gaps.
+func BezierWaveform(samples []float64, screenWidth, screenHeight int, offset float64, radiusControl float64, rng *rand.Rand) []Point {
 	centerX := float64(screenWidth) / 2
 	centerY := float64(screenHeight) / 2
-	radius := radiusControl * rand.Float64()
-
-	for i := 0; i < len(samples)-2; i += 3 {
-		angle1 := (float64(i) + offset) / float64(len(samples)) * 2 * math.Pi
-		angle2 := (float64(i+1) + offset) / float64(len(samples)) * 2 * math.Pi
-		angle3 := (float64(i+2) + offset) / float64(len(samples)) * 2 * math.Pi
-
-		x1 := centerX + radius*(1+samples[i])*math.Cos(angle1)
-		y1 := centerY + radius*(1+samples[i])*math.Sin(angle1)
-		x2 := centerX + radius*(1+samples[(i+1)%len(samples)])*math.Cos(angle2)
-		y2 := centerY + radius*(1+samples[(i+1)%len(samples)])*math.Sin(angle2)
-		x3 := centerX + radius*(1+samples[(i+2)%len(samples)])*math.Cos(angle3)
-		y3 := centerY + radius*(1+samples[(i+2)%len(samples)])*math.Sin(angle3)
+	radius := radiusControl * rng.Float64()
+
+	count := min(bezierPoints, len(samples))
+	points := make([]Point, count)
+	for i := range points {
+		// Average each bin of samples so the curve doesn't alias the waveform
+		start, end := i*len(samples)/count, (i+1)*len(samples)/count
+		var sum float64
+		for _, sample := range samples[start:end] {
+			sum += sample
+		}
+		level := sum / float64(end-start)
 
-		vertices = append(vertices, ebiten.Vertex{
-			DstX: float32(x1), DstY: float32(y1),
-			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
-		}, ebiten.Vertex{
-			DstX: float32(x2), DstY: float32(y2),
-			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
-		}, ebiten.Vertex{
-			DstX: float32(x3), DstY: float32(y3),
-			ColorR: 1, ColorG: 1, ColorB: 1, ColorA: 1,
-		})
+		angle := (float64(i) + offset) / float64(count) * 2 * math.Pi
+		points[i] = Point{
+			X: float32(centerX + radius*(1+level)*math.Cos(angle)),
+			Y: float32(centerY + radius*(1+level)*math.Sin(angle)),
+		}
 	}
+	return points
+}
 
-	return vertices
+// Point is a position on screen.
+type Point struct {
+	X, Y float32
+}
+
+// Vertex is a corner of a coloured triangle, with the fields of ebiten.Vertex
+// that the waveforms set.
+type Vertex struct {
+	DstX, DstY                     float32
+	SrcX, SrcY                     float32
+	ColorR, ColorG, ColorB, ColorA float32
 }